@@ -0,0 +1,126 @@
+package main
+
+import "math"
+
+// An axis-aligned bounding box is the region of space delimited by an interval on each axis
+type AABB struct {
+	X Interval
+	Y Interval
+	Z Interval
+}
+
+func NewAABB(x, y, z Interval) AABB {
+	return AABB{X: x, Y: y, Z: z}
+}
+
+// Returns the box having points a and b at opposite corners
+func NewAABBFromPoints(a, b Point3) AABB {
+	return AABB{
+		X: NewInterval(math.Min(a.X, b.X), math.Max(a.X, b.X)),
+		Y: NewInterval(math.Min(a.Y, b.Y), math.Max(a.Y, b.Y)),
+		Z: NewInterval(math.Min(a.Z, b.Z), math.Max(a.Z, b.Z))}
+}
+
+// Returns the smallest box that contains both a and b
+func NewSurroundingAABB(a, b AABB) AABB {
+	return AABB{X: NewSurroundingInterval(a.X, b.X), Y: NewSurroundingInterval(a.Y, b.Y), Z: NewSurroundingInterval(a.Z, b.Z)}
+}
+
+// Returns the interval of the given axis: 0 is X, 1 is Y, 2 is Z
+func (box AABB) Axis(n int) Interval {
+	switch n {
+	case 1:
+		return box.Y
+	case 2:
+		return box.Z
+	}
+
+	return box.X
+}
+
+// Returns the index of the axis along which the box is the longest
+func (box AABB) LongestAxis() int {
+	if box.X.Size() > box.Y.Size() {
+		if box.X.Size() > box.Z.Size() {
+			return 0
+		}
+		return 2
+	}
+
+	if box.Y.Size() > box.Z.Size() {
+		return 1
+	}
+
+	return 2
+}
+
+func (box AABB) Centroid() Point3 {
+	return NewPoint3((box.X.Min+box.X.Max)/2, (box.Y.Min+box.Y.Max)/2, (box.Z.Min+box.Z.Max)/2)
+}
+
+// Returns true if the box extends to infinity along some axis, e.g. the box of an infinite plane
+func (box AABB) IsInfinite() bool {
+	return math.IsInf(box.X.Size(), 0) || math.IsInf(box.Y.Size(), 0) || math.IsInf(box.Z.Size(), 0)
+}
+
+// The surface area is proportional to the probability that a random ray hits the box, which makes it the key
+// quantity of the surface area heuristic used to build a BVH
+func (box AABB) SurfaceArea() float64 {
+	dx, dy, dz := box.X.Size(), box.Y.Size(), box.Z.Size()
+
+	if dx < 0 || dy < 0 || dz < 0 {
+		return 0 // Empty box
+	}
+
+	return 2 * (dx*dy + dy*dz + dz*dx)
+}
+
+// Flat objects (e.g. a triangle lying on an axis plane) have a box with zero thickness, which can cause numerical
+// trouble: this function makes sure every side is at least delta long
+func (box AABB) Pad(delta float64) AABB {
+	pad := func(i Interval) Interval {
+		if i.Size() < delta {
+			return i.Expand(delta)
+		}
+		return i
+	}
+
+	return AABB{X: pad(box.X), Y: pad(box.Y), Z: pad(box.Z)}
+}
+
+// Checks whether the ray hits the box for some t in (rayTmin, rayTmax).
+//
+// This is the "slab" method: along each axis the box is the slab between two planes, and the ray enters and exits it at
+// values of t that we can easily compute. The ray hits the box if the three [enter, exit] intervals overlap.
+func (box AABB) Hit(ray Ray, rayTmin, rayTmax float64) bool {
+	origin := ray.Origin()
+	direction := ray.Direction()
+
+	for axis := 0; axis < 3; axis++ {
+		slab := box.Axis(axis)
+		o := origin.Axis(axis)
+		invD := 1 / direction.Axis(axis) // May be infinite, the comparisons below still work
+		t0 := (slab.Min - o) * invD
+		t1 := (slab.Max - o) * invD
+
+		if invD < 0 {
+			t0, t1 = t1, t0
+		}
+
+		if t0 > rayTmin {
+			rayTmin = t0
+		}
+
+		if t1 < rayTmax {
+			rayTmax = t1
+		}
+
+		if rayTmax <= rayTmin {
+			return false
+		}
+	}
+
+	return true
+}
+
+var EmptyAABB = NewAABB(Empty, Empty, Empty)
@@ -0,0 +1,183 @@
+package main
+
+import "math"
+
+// The background determines the color of the rays that don't hit any object, i.e. the light coming from the sky
+type Background interface {
+	Value(ray Ray) Color
+}
+
+// A background of a uniform color
+type SolidBackground struct {
+	color Color
+}
+
+func NewSolidBackground(c Color) SolidBackground {
+	return SolidBackground{color: c}
+}
+
+func (b SolidBackground) Value(ray Ray) Color {
+	return b.color
+}
+
+// A vertical gradient that blends from the bottom color (looking down) to the top color (looking up)
+type GradientBackground struct {
+	bottom Color
+	top    Color
+}
+
+func NewGradientBackground(bottom, top Color) GradientBackground {
+	return GradientBackground{bottom: bottom, top: top}
+}
+
+// Backgrounds that can pick directions proportionally to their brightness also implement this interface, so that the
+// renderer can aim rays explicitly at bright regions, such as the sun in a light probe, that would rarely be found by
+// scattering rays at random.
+type SampledBackground interface {
+	Background
+
+	// Returns a random direction, chosen with a probability roughly proportional to the brightness of the background
+	// in that direction, together with its probability density (with respect to the solid angle)
+	Sample(rng *Rng) (direction Vec3, pdf float64)
+
+	// Returns the probability density of Sample() returning the given direction
+	PDF(direction Vec3) float64
+}
+
+// The white to blue sky used since image 2
+func NewSkyBackground() GradientBackground {
+	return NewGradientBackground(NewColor(1, 1, 1), NewColor(0.5, 0.7, 1.0))
+}
+
+func (b GradientBackground) Value(ray Ray) Color {
+	unitDirection := ray.Direction().UnitVector() // All vector components are now between -1 and +1
+	alpha := (unitDirection.Y + 1) / 2            // Alpha is a number between 0 and 1 proportional to the Y component of the ray direction
+	return b.bottom.Mul(1 - alpha).Add(b.top.Mul(alpha))
+}
+
+// An environment background maps an image in latitude-longitude (also called equirectangular) format on a sphere
+// surrounding the whole scene: the horizontal image axis covers the 360 degrees around the vertical axis, while the
+// vertical image axis goes from straight up (top row) to straight down (bottom row).
+// With a high dynamic range image, such as a light probe loaded from a .hdr file, the environment lights the scene.
+type EnvironmentBackground struct {
+	image        *Framebuffer
+	rotation     float64 // Around the vertical axis, as a fraction of a full turn
+	intensity    float64
+	distribution *distribution2D // Used to sample the pixels proportionally to their brightness
+}
+
+func NewEnvironmentBackground(image *Framebuffer) EnvironmentBackground {
+	width, height := image.Width(), image.Height()
+	weights := make([]float64, width*height)
+
+	// Pixels close to the poles are stretched on the image and cover a smaller solid angle than the ones at the
+	// horizon, so their weight is scaled by the sine of their polar angle
+	for y := 0; y < height; y++ {
+		sinTheta := math.Sin(math.Pi * (float64(y) + 0.5) / float64(height))
+
+		for x := 0; x < width; x++ {
+			weights[y*width+x] = math.Max(0, Luminance(image.At(x, y))) * sinTheta
+		}
+	}
+
+	return EnvironmentBackground{image: image, intensity: 1, distribution: newDistribution2D(weights, width, height)}
+}
+
+// Rotates the environment counterclockwise (seen from above) around the vertical axis by the given angle in degrees
+func (b *EnvironmentBackground) SetRotation(degrees float64) {
+	b.rotation = degrees / 360
+}
+
+// Scales the radiance of the environment, e.g. to match the exposure of a light probe to the scene lights
+func (b *EnvironmentBackground) SetIntensity(intensity float64) {
+	b.intensity = intensity
+}
+
+// Returns the image coordinates, in the [0,1] range, seen in the given direction.
+// The center of the image is seen when looking towards the negative Z axis, i.e. the default camera direction.
+func directionToLatLong(direction Vec3) (u, v float64) {
+	d := direction.UnitVector()
+	u = 0.5 + math.Atan2(d.X, -d.Z)/(2*math.Pi)
+	v = math.Acos(math.Max(-1, math.Min(1, d.Y))) / math.Pi
+	return u, v
+}
+
+// Inverse of directionToLatLong()
+func latLongToDirection(u, v float64) Vec3 {
+	phi := 2 * math.Pi * (u - 0.5)
+	theta := math.Pi * v
+	sinTheta := math.Sin(theta)
+
+	return NewVec3(sinTheta*math.Sin(phi), math.Cos(theta), -sinTheta*math.Cos(phi))
+}
+
+func (b EnvironmentBackground) Value(ray Ray) Color {
+	u, v := directionToLatLong(ray.Direction())
+	u -= b.rotation
+	u -= math.Floor(u)
+
+	return b.bilinear(u, v).Mul(b.intensity)
+}
+
+// Blends the four pixels around the given image coordinates, wrapping around horizontally since the left and right
+// image edges meet on the sphere
+func (b EnvironmentBackground) bilinear(u, v float64) Color {
+	width, height := b.image.Width(), b.image.Height()
+
+	// Pixel centers are at half-integer coordinates
+	x := u*float64(width) - 0.5
+	y := v*float64(height) - 0.5
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	tx, ty := x-x0, y-y0
+
+	left := (int(x0)%width + width) % width
+	right := (left + 1) % width
+	top := clampInt(int(y0), 0, height-1)
+	bottom := clampInt(int(y0)+1, 0, height-1)
+
+	upper := b.image.At(left, top).Mul(1 - tx).Add(b.image.At(right, top).Mul(tx))
+	lower := b.image.At(left, bottom).Mul(1 - tx).Add(b.image.At(right, bottom).Mul(tx))
+
+	return upper.Mul(1 - ty).Add(lower.Mul(ty))
+}
+
+// Picks a pixel proportionally to its brightness, then a random direction within the pixel
+func (b EnvironmentBackground) Sample(rng *Rng) (direction Vec3, pdf float64) {
+	x, y, probability := b.distribution.sample(rng)
+
+	if probability == 0 {
+		return Vec3{}, 0 // The image is completely black
+	}
+
+	width, height := float64(b.image.Width()), float64(b.image.Height())
+	u := (float64(x) + rng.Double()) / width
+	v := (float64(y) + rng.Double()) / height
+
+	return latLongToDirection(u+b.rotation, v), b.solidAnglePDF(probability, v)
+}
+
+func (b EnvironmentBackground) PDF(direction Vec3) float64 {
+	u, v := directionToLatLong(direction)
+	u -= b.rotation
+	u -= math.Floor(u)
+
+	x := clampInt(int(u*float64(b.image.Width())), 0, b.image.Width()-1)
+	y := clampInt(int(v*float64(b.image.Height())), 0, b.image.Height()-1)
+
+	return b.solidAnglePDF(b.distribution.probability(x, y), v)
+}
+
+// Converts the probability of picking a pixel into a density with respect to the solid angle.
+// The density over the image is the probability divided by the pixel area, and mapping the [0,1]x[0,1] image on the
+// sphere stretches an area by 2*pi*pi*sin(theta).
+func (b EnvironmentBackground) solidAnglePDF(probability, v float64) float64 {
+	sinTheta := math.Sin(math.Pi * v)
+
+	if sinTheta <= 0 {
+		return 0
+	}
+
+	pixels := float64(b.image.Width() * b.image.Height())
+	return probability * pixels / (2 * math.Pi * math.Pi * sinTheta)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// A bounding volume hierarchy is a binary tree of bounding boxes: a ray that misses a node's box cannot hit any of the
+// objects below that node, so most objects are never tested and the cost of a hit test becomes roughly logarithmic
+// in the number of objects.
+type BVHNode struct {
+	left  Hittable
+	right Hittable // May be nil if the node wraps a single object
+	box   AABB
+	axis  int // The axis along which the children have been split
+}
+
+const (
+	bvhTraversalCost = 0.125 // Cost of testing a node's box relative to testing an object
+	bvhBins          = 16    // Number of candidate split positions evaluated on each axis
+	bvhMaxLeafSize   = 4     // Leaves never hold more objects than this
+)
+
+// Builds a BVH containing all the objects of the list, splitting them according to the surface area heuristic
+func NewBVHNode(list HittableList) BVHNode {
+	// Objects with an infinite box (e.g. planes) would make the surface area heuristic meaningless: they are kept out
+	// of the tree, and tested by every ray
+	bounded, infinite := NewHittableList(), NewHittableList()
+
+	for _, object := range list.objects {
+		if object.BoundingBox().IsInfinite() {
+			infinite.Add(object)
+		} else {
+			bounded.Add(object)
+		}
+	}
+
+	if len(infinite.objects) > 0 {
+		node := BVHNode{left: infinite, box: infinite.BoundingBox()}
+
+		if len(bounded.objects) > 0 {
+			node.right = NewBVHNode(bounded)
+			node.box = NewSurroundingAABB(node.box, node.right.BoundingBox())
+		}
+
+		return node
+	}
+
+	objects := bounded.objects // A copy of the list, as building the tree reorders the objects
+
+	if len(objects) == 0 {
+		return BVHNode{box: EmptyAABB}
+	}
+
+	root := buildBVH(objects)
+
+	if node, ok := root.(BVHNode); ok {
+		return node
+	}
+
+	return BVHNode{left: root, box: root.BoundingBox()}
+}
+
+// Implement the Hittable interface
+func (node BVHNode) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	if !node.box.Hit(ray, rayTmin, rayTmax) {
+		return false
+	}
+
+	// Visit the nearest child first, so that the farther one can be tested against a shorter ray
+	first, second := node.left, node.right
+	if ray.Direction().Axis(node.axis) < 0 && second != nil {
+		first, second = second, first
+	}
+
+	hitFirst := first != nil && first.Hit(ray, rayTmin, rayTmax, rec)
+
+	if hitFirst {
+		rayTmax = rec.T
+	}
+
+	hitSecond := second != nil && second.Hit(ray, rayTmin, rayTmax, rec)
+
+	return hitFirst || hitSecond
+}
+
+func (node BVHNode) BoundingBox() AABB {
+	return node.box
+}
+
+// Recursively builds the tree. The surface area heuristic estimates the cost of a split as the number of objects
+// on each side, weighted by the probability that a ray hitting the parent box also hits the child box (which is the
+// ratio of their surface areas). Candidate splits are taken at evenly spaced positions of the object centroids.
+func buildBVH(objects []Hittable) Hittable {
+	if len(objects) == 1 {
+		return objects[0]
+	}
+
+	boxes := make([]AABB, len(objects))
+	box := EmptyAABB
+	centroidBox := EmptyAABB
+
+	for i, object := range objects {
+		boxes[i] = object.BoundingBox()
+		box = NewSurroundingAABB(box, boxes[i])
+		c := boxes[i].Centroid()
+		centroidBox = NewSurroundingAABB(centroidBox, NewAABBFromPoints(c, c))
+	}
+
+	bestAxis, bestBin := -1, 0
+	bestCost := math.Inf(+1)
+
+	for axis := 0; axis < 3; axis++ {
+		extent := centroidBox.Axis(axis)
+
+		if extent.Size() <= 0 {
+			continue // All centroids are at the same position, no way to split them along this axis
+		}
+
+		var binBoxes [bvhBins]AABB
+		var binCounts [bvhBins]int
+
+		for i := range binBoxes {
+			binBoxes[i] = EmptyAABB
+		}
+
+		for _, b := range boxes {
+			bin := bvhBin(b, axis, extent)
+			binBoxes[bin] = NewSurroundingAABB(binBoxes[bin], b)
+			binCounts[bin]++
+		}
+
+		// Sweep from the right to collect the area and count of the objects past each split position
+		var rightAreas [bvhBins]float64
+		var rightCounts [bvhBins]int
+		rightBox := EmptyAABB
+		count := 0
+
+		for i := bvhBins - 1; i > 0; i-- {
+			rightBox = NewSurroundingAABB(rightBox, binBoxes[i])
+			count += binCounts[i]
+			rightAreas[i] = rightBox.SurfaceArea()
+			rightCounts[i] = count
+		}
+
+		// Then sweep from the left to evaluate the cost of splitting between bin i-1 and bin i
+		leftBox := EmptyAABB
+		count = 0
+
+		for i := 1; i < bvhBins; i++ {
+			leftBox = NewSurroundingAABB(leftBox, binBoxes[i-1])
+			count += binCounts[i-1]
+
+			if count == 0 || rightCounts[i] == 0 {
+				continue
+			}
+
+			cost := bvhTraversalCost + (leftBox.SurfaceArea()*float64(count)+rightAreas[i]*float64(rightCounts[i]))/box.SurfaceArea()
+
+			if cost < bestCost {
+				bestAxis, bestBin, bestCost = axis, i, cost
+			}
+		}
+	}
+
+	// Making a leaf costs one hit test per object
+	if len(objects) <= bvhMaxLeafSize && bestCost >= float64(len(objects)) {
+		return newBVHLeaf(objects)
+	}
+
+	if bestAxis < 0 {
+		// All objects share the same centroid but there are too many of them for a leaf: just split them in half
+		axis := box.LongestAxis()
+		sort.Slice(objects, func(i, j int) bool {
+			return objects[i].BoundingBox().Axis(axis).Min < objects[j].BoundingBox().Axis(axis).Min
+		})
+
+		mid := len(objects) / 2
+
+		return BVHNode{left: buildBVH(objects[:mid]), right: buildBVH(objects[mid:]), box: box, axis: axis}
+	}
+
+	// Move the objects on the left of the split position to the beginning of the slice
+	extent := centroidBox.Axis(bestAxis)
+	mid := 0
+
+	for i := range objects {
+		if bvhBin(boxes[i], bestAxis, extent) < bestBin {
+			objects[i], objects[mid] = objects[mid], objects[i]
+			boxes[i], boxes[mid] = boxes[mid], boxes[i]
+			mid++
+		}
+	}
+
+	return BVHNode{left: buildBVH(objects[:mid]), right: buildBVH(objects[mid:]), box: box, axis: bestAxis}
+}
+
+// Returns the bin containing the centroid of the box
+func bvhBin(box AABB, axis int, extent Interval) int {
+	bin := int(bvhBins * (box.Centroid().Axis(axis) - extent.Min) / extent.Size())
+
+	if bin >= bvhBins {
+		bin = bvhBins - 1
+	}
+
+	return bin
+}
+
+func newBVHLeaf(objects []Hittable) Hittable {
+	leaf := NewHittableList()
+	for _, object := range objects {
+		leaf.Add(object)
+	}
+
+	return leaf
+}
@@ -0,0 +1,93 @@
+package main
+
+import "testing"
+
+func TestAABBHit(t *testing.T) {
+	box := NewAABBFromPoints(NewPoint3(1, 1, 1), NewPoint3(-1, -1, -1))
+
+	if !box.Hit(NewRay(NewPoint3(0, 0, -5), NewVec3(0, 0, 1)), 0, 100) {
+		t.Errorf("ray through the box center should hit it")
+	}
+
+	if box.Hit(NewRay(NewPoint3(0, 0, -5), NewVec3(0, 0, -1)), 0, 100) {
+		t.Errorf("ray pointing away from the box should miss it")
+	}
+
+	if box.Hit(NewRay(NewPoint3(0, 0, -5), NewVec3(0, 0, 1)), 0, 3) {
+		t.Errorf("ray too short to reach the box should miss it")
+	}
+
+	if box.Hit(NewRay(NewPoint3(0, 2, -5), NewVec3(0, 0, 1)), 0, 100) {
+		t.Errorf("ray parallel to the box should miss it")
+	}
+}
+
+func TestBVHMatchesHittableList(t *testing.T) {
+	rng := NewRng(1)
+	world := NewHittableList()
+
+	// Overlapping spheres of various sizes, including hollow ones and a few sharing the same center
+	for i := 0; i < 500; i++ {
+		center := rng.Vec3InInterval(-10, 10)
+		radius := rng.DoubleInInterval(0.1, 1.5)
+		if i%50 == 0 {
+			radius = -radius
+		}
+
+		world.Add(NewSphere(center, radius))
+
+		if i%100 == 0 {
+			world.Add(NewSphere(center, radius/2))
+		}
+	}
+
+	bvh := NewBVHNode(world)
+
+	if bvh.BoundingBox() != world.BoundingBox() {
+		t.Errorf("bounding box mismatch: %v != %v", bvh.BoundingBox(), world.BoundingBox())
+	}
+
+	hits := 0
+
+	for i := 0; i < 20000; i++ {
+		ray := NewRay(rng.Vec3InInterval(-15, 15), rng.Vec3InInterval(-1, 1))
+		tmin := 0.001
+		tmax := rng.DoubleInInterval(1, 40)
+
+		listRec := HitRecord{}
+		bvhRec := HitRecord{}
+		listHit := world.Hit(ray, tmin, tmax, &listRec)
+		bvhHit := bvh.Hit(ray, tmin, tmax, &bvhRec)
+
+		if listHit != bvhHit {
+			t.Fatalf("ray %v: list hit is %v, BVH hit is %v", ray, listHit, bvhHit)
+		}
+
+		if listHit {
+			hits++
+			if listRec.T != bvhRec.T || listRec.P != bvhRec.P || listRec.Normal != bvhRec.Normal || listRec.FrontFace != bvhRec.FrontFace {
+				t.Fatalf("ray %v: list record %+v != BVH record %+v", ray, listRec, bvhRec)
+			}
+		}
+	}
+
+	if hits == 0 {
+		t.Errorf("no ray hit the scene, the test is meaningless")
+	}
+}
+
+func TestBVHOfFewObjects(t *testing.T) {
+	empty := NewBVHNode(NewHittableList())
+	if empty.Hit(NewRay(NewPoint3(0, 0, 0), NewVec3(0, 0, -1)), 0, 100, &HitRecord{}) {
+		t.Errorf("empty BVH should never be hit")
+	}
+
+	list := NewHittableList()
+	list.Add(NewSphere(NewPoint3(0, 0, -2), 0.5))
+	single := NewBVHNode(list)
+
+	rec := HitRecord{}
+	if !single.Hit(NewRay(NewPoint3(0, 0, 0), NewVec3(0, 0, -1)), 0, 100, &rec) || rec.T != 1.5 {
+		t.Errorf("single object BVH should be hit at t=1.5, got %+v", rec)
+	}
+}
@@ -1,10 +1,6 @@
 package main
 
-import (
-	"fmt"
-	"io"
-	"math"
-)
+import "math"
 
 const (
 	// Viewport
@@ -28,10 +24,11 @@ type Camera struct {
 	pixelUpperLeft Point3
 	// Public members
 	HitRayTmin float64
+	Background Background // Color of the rays that don't hit any object
 }
 
 func NewCamera() Camera {
-	return Camera{imageWidth: ImageWidth, aspectRatio: AspectRatio, HitRayTmin: 0}
+	return Camera{imageWidth: ImageWidth, aspectRatio: AspectRatio, HitRayTmin: 0, Background: NewSkyBackground()}
 }
 
 func (camera *Camera) Initialize() {
@@ -63,15 +60,14 @@ func (camera Camera) RayColor(ray Ray, world Hittable) Color {
 		return rec.Normal.Add(NewVec3(1, 1, 1)).Div(2)
 	}
 
-	return i2_rayColor(ray) // Reuse gradient background from image 2
+	return camera.Background.Value(ray) // By default it is the gradient background from image 2
 }
 
-func (camera *Camera) Render(w io.Writer, world Hittable) {
+func (camera *Camera) Render(world Hittable) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -80,16 +76,11 @@ func (camera *Camera) Render(w io.Writer, world Hittable) {
 			ray := NewRay(camera.center, direction)
 			c := camera.RayColor(ray, world)
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
 
 // Returns a random point in the square surrounding a pixel at the origin
@@ -113,12 +104,11 @@ func (camera Camera) getRay(i, j int) Ray {
 }
 
 // Casts multiple rays per pixel in order to get a higher quality, antialiased image
-func (camera *Camera) RenderWithMultipleSamples(w io.Writer, world Hittable, samplesPerPixel int) {
+func (camera *Camera) RenderWithMultipleSamples(world Hittable, samplesPerPixel int) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -132,16 +122,11 @@ func (camera *Camera) RenderWithMultipleSamples(w io.Writer, world Hittable, sam
 
 			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
 
 // Simulation of a diffuse (matte) material
@@ -162,15 +147,14 @@ func (camera Camera) RayColorOfDiffuseMaterial(ray Ray, world Hittable, depth in
 		return camera.RayColorOfDiffuseMaterial(NewRay(rec.P, direction), world, depth-1).Mul(0.5)
 	}
 
-	return i2_rayColor(ray) // Reuse gradient background from image 2
+	return camera.Background.Value(ray) // By default it is the gradient background from image 2
 }
 
-func (camera *Camera) RenderWithDiffuseMaterial(w io.Writer, world Hittable, samplesPerPixel, maxRayDepth int) {
+func (camera *Camera) RenderWithDiffuseMaterial(world Hittable, samplesPerPixel, maxRayDepth int) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -184,16 +168,11 @@ func (camera *Camera) RenderWithDiffuseMaterial(w io.Writer, world Hittable, sam
 
 			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
 
 // Simulation of a diffuse (matte) material with the Lambert model
@@ -214,15 +193,14 @@ func (camera Camera) RayColorOfLambertianMaterial(ray Ray, world Hittable, depth
 		return camera.RayColorOfDiffuseMaterial(NewRay(rec.P, direction), world, depth-1).Mul(reflectance)
 	}
 
-	return i2_rayColor(ray) // Reuse gradient background from image 2
+	return camera.Background.Value(ray) // By default it is the gradient background from image 2
 }
 
-func (camera *Camera) RenderWithLambertianMaterial(w io.Writer, world Hittable, samplesPerPixel, maxRayDepth int) {
+func (camera *Camera) RenderWithLambertianMaterial(world Hittable, samplesPerPixel, maxRayDepth int) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -236,24 +214,20 @@ func (camera *Camera) RenderWithLambertianMaterial(w io.Writer, world Hittable,
 
 			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
 
-func (camera *Camera) RenderGamut(w io.Writer, world Hittable, samplesPerPixel, maxRayDepth int, gammaCorrection bool) {
+func (camera *Camera) RenderGamut(world Hittable, samplesPerPixel, maxRayDepth int, gammaCorrection bool) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	if !gammaCorrection {
+		fb.DisableGammaCorrection()
+	}
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -270,22 +244,11 @@ func (camera *Camera) RenderGamut(w io.Writer, world Hittable, samplesPerPixel,
 
 			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
 
-			if gammaCorrection {
-				c.X = LinearToGamma(c.X)
-				c.Y = LinearToGamma(c.Y)
-				c.Z = LinearToGamma(c.Z)
-			}
-
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
 
 // The following function uses the properties of the object material to properly compute the ray color
@@ -300,7 +263,7 @@ func (camera Camera) RayColorOfObjectMaterial(ray Ray, world Hittable, depth int
 		scattered := Ray{}
 		attenuation := Color{}
 
-		if rec.Mat.Scatter(ray, &rec, &attenuation, &scattered) {
+		if rec.Mat.Scatter(globalRng, ray, &rec, &attenuation, &scattered) {
 			c := camera.RayColorOfObjectMaterial(scattered, world, depth-1)
 
 			return Color{c.X * attenuation.X, c.Y * attenuation.Y, c.Z * attenuation.Z}
@@ -309,15 +272,13 @@ func (camera Camera) RayColorOfObjectMaterial(ray Ray, world Hittable, depth int
 		return Color{0, 0, 0}
 	}
 
-	return i2_rayColor(ray) // Reuse gradient background from image 2
+	return camera.Background.Value(ray) // By default it is the gradient background from image 2
 }
 
-func (camera *Camera) RenderWithObjectMaterial(w io.Writer, world Hittable, samplesPerPixel, maxRayDepth int) {
+func (camera *Camera) RenderWithObjectMaterial(world Hittable, samplesPerPixel, maxRayDepth int) *Framebuffer {
 	camera.Initialize()
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
 
 	for y := 0; y < camera.imageHeight; y++ {
 		for x := 0; x < camera.imageWidth; x++ {
@@ -331,19 +292,9 @@ func (camera *Camera) RenderWithObjectMaterial(w io.Writer, world Hittable, samp
 
 			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
 
-			// Apply gamma correction
-			c.X = LinearToGamma(c.X)
-			c.Y = LinearToGamma(c.Y)
-			c.Z = LinearToGamma(c.Z)
-
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
@@ -0,0 +1,125 @@
+package main
+
+import "math"
+
+// Complex index of refraction of a conductor, per color channel: light that enters a metal is quickly absorbed, k
+// (the extinction coefficient) tells how quickly
+type ComplexIOR struct {
+	Eta Color
+	K   Color
+}
+
+// Measured indices of common metals, at the wavelengths of red, green and blue light
+var (
+	Gold      = ComplexIOR{Eta: NewColor(0.143119, 0.374957, 1.44248), K: NewColor(3.98316, 2.38572, 1.60322)}
+	Copper    = ComplexIOR{Eta: NewColor(0.200438, 0.924033, 1.10221), K: NewColor(3.91295, 2.45285, 2.14219)}
+	Aluminium = ComplexIOR{Eta: NewColor(1.65746, 0.880369, 0.521229), K: NewColor(9.22387, 6.26952, 4.837)}
+	Silver    = ComplexIOR{Eta: NewColor(0.155265, 0.116723, 0.138342), K: NewColor(4.82835, 3.12225, 2.14696)}
+)
+
+// A metal whose surface is made of microfacets, see ggxDistribution. Unlike MetalMaterial, the color of the
+// reflections comes from the index of refraction and changes with the angle, towards white at grazing angles.
+type ConductorMaterial struct {
+	ior          ComplexIOR
+	distribution ggxDistribution
+}
+
+// Conductor material with roughness in [0, 1] along the tangent (u) and the bitangent (v) of the surface.
+// A roughness of 0 gives a perfect mirror.
+func NewConductorMaterial(ior ComplexIOR, roughnessU, roughnessV float64) ConductorMaterial {
+	return ConductorMaterial{ior: ior, distribution: newGGXDistribution(roughnessU, roughnessV)}
+}
+
+// Fraction of the light reflected by a conductor, for unpolarized light hitting it at an angle whose cosine is cosTheta
+func FresnelConductor(cosTheta float64, ior ComplexIOR) Color {
+	channel := func(eta, k float64) float64 {
+		cos2 := cosTheta * cosTheta
+		sin2 := 1 - cos2
+		eta2, k2 := eta*eta, k*k
+
+		t0 := eta2 - k2 - sin2
+		a2PlusB2 := math.Sqrt(t0*t0 + 4*eta2*k2)
+		a := math.Sqrt(math.Max(0, (a2PlusB2+t0)/2))
+
+		// Perpendicular polarization
+		t1 := a2PlusB2 + cos2
+		t2 := 2 * cosTheta * a
+		rs := (t1 - t2) / (t1 + t2)
+
+		// Parallel polarization
+		t3 := cos2*a2PlusB2 + sin2*sin2
+		t4 := t2 * sin2
+		rp := rs * (t3 - t4) / (t3 + t4)
+
+		return (rs + rp) / 2
+	}
+
+	cosTheta = math.Min(math.Max(cosTheta, 0), 1)
+
+	return NewColor(channel(ior.Eta.X, ior.K.X), channel(ior.Eta.Y, ior.K.Y), channel(ior.Eta.Z, ior.K.Z))
+}
+
+func (m ConductorMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	sample, ok := m.Sample(rng, ray, rec)
+
+	if !ok {
+		return false
+	}
+
+	*scattered = NewRayWithTime(rec.P, sample.Direction, ray.Time())
+	*attenuation = sample.Attenuation
+
+	return true
+}
+
+func (m ConductorMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	wo := ray.Direction().UnitVector().Negate()
+	localWo, ok := outgoingInShadingFrame(rec, wo)
+
+	if !ok {
+		return ScatterSample{}, false
+	}
+
+	if m.distribution.smooth() {
+		direction := Reflect(wo.Negate(), rec.Normal)
+		return ScatterSample{Direction: direction, Attenuation: FresnelConductor(localWo.Z, m.ior), Specular: true}, true
+	}
+
+	localWi, ok := m.distribution.sampleReflection(rng, localWo)
+
+	if !ok {
+		return ScatterSample{}, false // Reflected into the surface by a steep microfacet
+	}
+
+	sample := ScatterSample{Direction: fromShadingFrame(rec, localWi), PDF: m.distribution.reflectionPDF(localWo, localWi)}
+	sample.Attenuation = m.eval(localWo, localWi).Div(sample.PDF)
+
+	return sample, true
+}
+
+// The scattering function is F * D * G2 / (4 * cos(wo) * cos(wi)), times cos(wi)
+func (m ConductorMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	if m.distribution.smooth() {
+		return Color{0, 0, 0}
+	}
+
+	return m.eval(toShadingFrame(rec, wo), toShadingFrame(rec, wi))
+}
+
+func (m ConductorMaterial) eval(wo, wi Vec3) Color {
+	f, h := m.distribution.reflection(wo, wi)
+
+	if f == 0 {
+		return Color{0, 0, 0}
+	}
+
+	return FresnelConductor(wo.Dot(h), m.ior).Mul(f)
+}
+
+func (m ConductorMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	if m.distribution.smooth() {
+		return 0
+	}
+
+	return m.distribution.reflectionPDF(toShadingFrame(rec, wo), toShadingFrame(rec, wi))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFresnelConductor(t *testing.T) {
+	// At normal incidence the reflectance is ((eta-1)² + k²) / ((eta+1)² + k²)
+	r := FresnelConductor(1, Gold)
+	eta, k := Gold.Eta.X, Gold.K.X
+
+	if want := ((eta-1)*(eta-1) + k*k) / ((eta+1)*(eta+1) + k*k); math.Abs(r.X-want) > 1e-9 {
+		t.Errorf("reflectance of gold at normal incidence is %v, want %v", r.X, want)
+	}
+
+	// Gold reflects more red than blue
+	if r.X <= r.Z {
+		t.Errorf("reflectance of gold at normal incidence is %v", r)
+	}
+
+	// All metals become perfect mirrors at grazing angles
+	if r := FresnelConductor(0, Aluminium); math.Abs(r.X-1) > 1e-9 || math.Abs(r.Z-1) > 1e-9 {
+		t.Errorf("reflectance of aluminium at a grazing angle is %v", r)
+	}
+}
+
+func TestConductorSampling(t *testing.T) {
+	materials := map[string]ConductorMaterial{
+		"isotropic":   NewConductorMaterial(Copper, 0.4, 0.4),
+		"anisotropic": NewConductorMaterial(Silver, 0.2, 0.7),
+	}
+
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), FrontFace: true}
+	rec.SetTangentFrame(NewVec3(1, 0, 0))
+	wo := NewVec3(0.5, 0.2, 0.8).UnitVector()
+
+	for name, m := range materials {
+		t.Run(name, func(t *testing.T) {
+			checkSampling(t, m, &rec, wo)
+		})
+	}
+}
+
+// A surface that is smooth in one direction only is still rough, and must not divide by its zero width
+func TestConductorSmoothInOneDirection(t *testing.T) {
+	checkSmoothInOneDirection(t, NewConductorMaterial(Gold, 0.5, 0))
+}
@@ -0,0 +1,69 @@
+package main
+
+// The Cornell box is a standard test scene: a room with a red and a green wall, lit by an area light in the ceiling,
+// containing two white boxes. Comparing renders against it shows whether light bounces around correctly.
+func NewCornellBoxScene() (HittableList, PositionableCamera) {
+	world := NewHittableList()
+
+	red := NewLambertianMaterial(NewColor(0.65, 0.05, 0.05))
+	white := NewLambertianMaterial(NewColor(0.73, 0.73, 0.73))
+	green := NewLambertianMaterial(NewColor(0.12, 0.45, 0.15))
+	light := NewDiffuseLight(NewColor(15, 15, 15))
+
+	// The room is a cube of side 555 and all walls face inwards
+	world.Add(NewQuad(NewPoint3(555, 0, 0), NewVec3(0, 0, 555), NewVec3(0, 555, 0), green)) // Left
+	world.Add(NewQuad(NewPoint3(0, 0, 0), NewVec3(0, 555, 0), NewVec3(0, 0, 555), red))     // Right
+	world.Add(NewQuad(NewPoint3(0, 0, 0), NewVec3(0, 0, 555), NewVec3(555, 0, 0), white))   // Floor
+	world.Add(NewQuad(NewPoint3(0, 555, 0), NewVec3(555, 0, 0), NewVec3(0, 0, 555), white)) // Ceiling
+	world.Add(NewQuad(NewPoint3(0, 0, 555), NewVec3(0, 555, 0), NewVec3(555, 0, 0), white)) // Back
+
+	// The light hangs just below the ceiling and faces down, since it only emits from its front face
+	lamp := NewQuad(NewPoint3(343, 554, 332), NewVec3(-130, 0, 0), NewVec3(0, 0, -105), light)
+	world.Add(lamp)
+
+	tallBox := NewBox(NewPoint3(0, 0, 0), NewPoint3(165, 330, 165), white)
+	world.Add(NewInstance(tallBox, NewRotationY(15).Then(NewTranslation(NewVec3(265, 0, 295)))))
+
+	shortBox := NewBox(NewPoint3(0, 0, 0), NewPoint3(165, 165, 165), white)
+	world.Add(NewInstance(shortBox, NewRotationY(-18).Then(NewTranslation(NewVec3(130, 0, 65)))))
+
+	cam := NewPositionableCamera()
+	cam.SetAspectRatio(1)
+	cam.SetImageWidth(600)
+	cam.SetSamplesPerPixel(200)
+	cam.SetMaxRayDepth(50)
+	cam.SetBackground(NewSolidBackground(NewColor(0, 0, 0))) // The only light comes from the ceiling
+	cam.SetLights(lamp)
+
+	cam.SetVerticalFieldOfView(40)
+	cam.SetLookFrom(NewPoint3(278, 278, -800))
+	cam.SetLookAt(NewPoint3(278, 278, 0))
+
+	return world, cam
+}
+
+// Average luminance of the linear colors of a converged render of the Cornell box, with the default camera and
+// a maximum ray depth of 50. Renders with the same scene must match it, whatever the sampling strategy.
+const CornellBoxReferenceLuminance = 0.1574
+
+// Image 24, the Cornell box
+func CornellBox(options RenderOptions) *Framebuffer {
+	world, cam := NewCornellBoxScene()
+
+	options.Apply(&cam)
+
+	return cam.Render(NewBVHNode(world))
+}
+
+// Returns the average luminance of the linear colors of the image, i.e. the amount of light that reached the camera
+func AverageLuminance(fb *Framebuffer) float64 {
+	sum := 0.0
+
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			sum += Luminance(fb.At(x, y))
+		}
+	}
+
+	return sum / float64(fb.Width()*fb.Height())
+}
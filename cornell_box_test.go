@@ -0,0 +1,20 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// A small and noisy render must still receive the same amount of light as the reference
+func TestCornellBoxEnergy(t *testing.T) {
+	world, cam := NewCornellBoxScene()
+	cam.SetImageWidth(32)
+	cam.SetSamplesPerPixel(128)
+
+	SetRandomSeed(1)
+	luminance := AverageLuminance(cam.Render(NewBVHNode(world)))
+
+	if math.Abs(luminance/CornellBoxReferenceLuminance-1) > 0.05 {
+		t.Errorf("average luminance %v, want %v within 5%%", luminance, CornellBoxReferenceLuminance)
+	}
+}
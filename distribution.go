@@ -0,0 +1,84 @@
+package main
+
+import "sort"
+
+// A piecewise constant probability distribution over n intervals: each interval is picked with a probability
+// proportional to its weight. It is used to pick bright regions of light sources more often than dark ones.
+type distribution1D struct {
+	cdf   []float64 // The probability of picking an interval before i is cdf[i], cdf[n] is 1
+	total float64   // Sum of the weights
+}
+
+func newDistribution1D(weights []float64) distribution1D {
+	d := distribution1D{cdf: make([]float64, len(weights)+1)}
+
+	for i, w := range weights {
+		d.cdf[i+1] = d.cdf[i] + w
+	}
+
+	d.total = d.cdf[len(weights)]
+
+	if d.total > 0 {
+		for i := range d.cdf {
+			d.cdf[i] /= d.total
+		}
+	}
+
+	return d
+}
+
+// Returns the interval that contains the given random number in [0,1) and its probability.
+// Intervals with zero weight are never picked; if all weights are zero, the returned probability is zero.
+func (d distribution1D) sample(xi float64) (index int, probability float64) {
+	n := len(d.cdf) - 1
+
+	if d.total <= 0 {
+		return 0, 0
+	}
+
+	index = sort.Search(n, func(i int) bool { return d.cdf[i+1] > xi })
+	index = clampInt(index, 0, n-1)
+
+	return index, d.probability(index)
+}
+
+func (d distribution1D) probability(index int) float64 {
+	if d.total <= 0 {
+		return 0
+	}
+
+	return d.cdf[index+1] - d.cdf[index]
+}
+
+// A piecewise constant distribution over the cells of a grid, e.g. the pixels of an image.
+// A row is picked first from the marginal distribution, then a column from the distribution of that row.
+type distribution2D struct {
+	rows     []distribution1D
+	marginal distribution1D
+}
+
+// Weights are given row by row
+func newDistribution2D(weights []float64, width, height int) *distribution2D {
+	d := &distribution2D{rows: make([]distribution1D, height)}
+	rowWeights := make([]float64, height)
+
+	for y := range d.rows {
+		d.rows[y] = newDistribution1D(weights[y*width : (y+1)*width])
+		rowWeights[y] = d.rows[y].total
+	}
+
+	d.marginal = newDistribution1D(rowWeights)
+
+	return d
+}
+
+func (d *distribution2D) sample(rng *Rng) (x, y int, probability float64) {
+	y, pRow := d.marginal.sample(rng.Double())
+	x, pColumn := d.rows[y].sample(rng.Double())
+
+	return x, y, pRow * pColumn
+}
+
+func (d *distribution2D) probability(x, y int) float64 {
+	return d.marginal.probability(y) * d.rows[y].probability(x)
+}
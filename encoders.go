@@ -0,0 +1,127 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+)
+
+// An Encoder writes a framebuffer to w using some image format
+type Encoder func(w io.Writer, fb *Framebuffer) error
+
+// Returns the encoder matching the extension of the given file name
+func EncoderForFilename(filename string) (Encoder, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".png":
+		return EncodePNG, nil
+	case ".ppm":
+		return EncodePPM, nil
+	case ".pfm":
+		return EncodePFM, nil
+	}
+
+	return nil, fmt.Errorf("unsupported output format %q, use one of .png, .ppm or .pfm", filepath.Ext(filename))
+}
+
+// Translates a display color component in the [0,1] range to the [0,255] range
+func colorComponentToByte(c float64) int {
+	return int(255.999 * c)
+}
+
+// Writes the framebuffer in the plain text PPM format (P3), which is easy to read but takes a lot of space
+func EncodePlainPPM(w io.Writer, fb *Framebuffer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "P3\n") // Magic
+	fmt.Fprintf(bw, "%d %d\n", fb.Width(), fb.Height())
+	fmt.Fprintf(bw, "255\n") // Maximum value of a color component
+
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			c := fb.DisplayColor(x, y)
+
+			ir := colorComponentToByte(c.X)
+			ig := colorComponentToByte(c.Y)
+			ib := colorComponentToByte(c.Z)
+
+			fmt.Fprintf(bw, "%d %d %d\n", ir, ig, ib)
+		}
+		fmt.Fprintln(bw)
+	}
+
+	fmt.Fprintln(bw)
+
+	return bw.Flush()
+}
+
+// Writes the framebuffer in the binary PPM format (P6): same header as the plain format, followed by one byte per color component
+func EncodePPM(w io.Writer, fb *Framebuffer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "P6\n") // Magic
+	fmt.Fprintf(bw, "%d %d\n", fb.Width(), fb.Height())
+	fmt.Fprintf(bw, "255\n") // Maximum value of a color component, also a single whitespace must separate it from the pixel data
+
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			c := fb.DisplayColor(x, y)
+
+			bw.WriteByte(byte(colorComponentToByte(c.X)))
+			bw.WriteByte(byte(colorComponentToByte(c.Y)))
+			bw.WriteByte(byte(colorComponentToByte(c.Z)))
+		}
+	}
+
+	return bw.Flush()
+}
+
+// Writes the framebuffer as a PNG image, with 8 bits per color component
+func EncodePNG(w io.Writer, fb *Framebuffer) error {
+	img := image.NewNRGBA(image.Rect(0, 0, fb.Width(), fb.Height()))
+
+	for y := 0; y < fb.Height(); y++ {
+		for x := 0; x < fb.Width(); x++ {
+			c := fb.DisplayColor(x, y)
+
+			img.SetNRGBA(x, y, color.NRGBA{
+				R: uint8(colorComponentToByte(c.X)),
+				G: uint8(colorComponentToByte(c.Y)),
+				B: uint8(colorComponentToByte(c.Z)),
+				A: 255})
+		}
+	}
+
+	return png.Encode(w, img)
+}
+
+// Writes the framebuffer in the Portable Float Map format, which keeps the linear colors (i.e. the radiance) without
+// any gamma correction or clamping, so the image can be post-processed later
+func EncodePFM(w io.Writer, fb *Framebuffer) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "PF\n") // Magic for a color image
+	fmt.Fprintf(bw, "%d %d\n", fb.Width(), fb.Height())
+	fmt.Fprintf(bw, "-1.0\n") // A negative scale means the data is little endian
+
+	// Rows are stored from bottom to top, each component is a 32-bit float
+	buf := make([]byte, 4)
+	for y := fb.Height() - 1; y >= 0; y-- {
+		for x := 0; x < fb.Width(); x++ {
+			c := fb.At(x, y)
+
+			for _, v := range []float64{c.X, c.Y, c.Z} {
+				binary.LittleEndian.PutUint32(buf, math.Float32bits(float32(v)))
+				bw.Write(buf)
+			}
+		}
+	}
+
+	return bw.Flush()
+}
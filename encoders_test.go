@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/png"
+	"math"
+	"testing"
+)
+
+func newTestFramebuffer() *Framebuffer {
+	fb := NewFramebuffer(3, 2)
+	fb.Set(0, 0, NewColor(1, 0, 0))
+	fb.Set(1, 0, NewColor(0, 0.25, 0))
+	fb.Set(2, 1, NewColor(4, 0, 0.5)) // Out of the displayable range
+	return fb
+}
+
+func TestEncodePPM(t *testing.T) {
+	var b bytes.Buffer
+	EncodePPM(&b, newTestFramebuffer())
+
+	header := "P6\n3 2\n255\n"
+	if !bytes.HasPrefix(b.Bytes(), []byte(header)) {
+		t.Fatalf("bad header %q", b.String())
+	}
+
+	data := b.Bytes()[len(header):]
+	if len(data) != 3*2*3 {
+		t.Fatalf("got %d bytes of pixel data, want %d", len(data), 3*2*3)
+	}
+
+	// The second pixel is gamma corrected, the last one is clamped
+	if data[0] != 255 || data[4] != 127 || data[15] != 255 || data[17] != 181 {
+		t.Errorf("pixel data mismatch: %v", data)
+	}
+}
+
+func TestEncodePNG(t *testing.T) {
+	var b bytes.Buffer
+	if err := EncodePNG(&b, newTestFramebuffer()); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := png.Decode(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, _, _, _ := img.At(2, 1).RGBA()
+	if r>>8 != 255 || img.Bounds().Dx() != 3 || img.Bounds().Dy() != 2 {
+		t.Errorf("decoded image mismatch")
+	}
+}
+
+func TestEncodePFM(t *testing.T) {
+	var b bytes.Buffer
+	EncodePFM(&b, newTestFramebuffer())
+
+	header := "PF\n3 2\n-1.0\n"
+	if !bytes.HasPrefix(b.Bytes(), []byte(header)) {
+		t.Fatalf("bad header %q", b.String())
+	}
+
+	// Rows are bottom to top, so the first values belong to pixel 0, 1; the linear value of pixel 2, 1 is preserved
+	data := b.Bytes()[len(header):]
+	red := math.Float32frombits(binary.LittleEndian.Uint32(data[2*12:]))
+	if red != 4 {
+		t.Errorf("got %f, want 4", red)
+	}
+}
+
+func TestEncoderForFilename(t *testing.T) {
+	for _, name := range []string{"out.png", "OUT.PPM", "dir/out.pfm"} {
+		if _, err := EncoderForFilename(name); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	if _, err := EncoderForFilename("out.gif"); err == nil {
+		t.Errorf("out.gif should not be supported")
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+// A Framebuffer holds the linear colors of a rendered image, one per pixel.
+// Renderers fill it and encoders turn it into an image file, so neither needs to know about the other.
+type Framebuffer struct {
+	width           int
+	height          int
+	pixels          []Color // Stored row by row, starting from the top left pixel
+	gammaCorrection bool    // Whether colors must be gamma corrected before being displayed
+}
+
+func NewFramebuffer(width, height int) *Framebuffer {
+	return &Framebuffer{width: width, height: height, pixels: make([]Color, width*height), gammaCorrection: true}
+}
+
+func (fb *Framebuffer) Width() int {
+	return fb.width
+}
+
+func (fb *Framebuffer) Height() int {
+	return fb.height
+}
+
+// The first images of the book are displayed without gamma correction
+func (fb *Framebuffer) DisableGammaCorrection() {
+	fb.gammaCorrection = false
+}
+
+// Returns the linear color of the pixel at location x, y
+func (fb *Framebuffer) At(x, y int) Color {
+	return fb.pixels[y*fb.width+x]
+}
+
+// Sets the linear color of the pixel at location x, y.
+// Different goroutines may safely set different pixels at the same time.
+func (fb *Framebuffer) Set(x, y int, c Color) {
+	fb.pixels[y*fb.width+x] = c
+}
+
+// Returns the color of the pixel at location x, y ready to be displayed, i.e. gamma corrected (if enabled) and
+// with each component clamped to the [0,1] range
+func (fb *Framebuffer) DisplayColor(x, y int) Color {
+	c := fb.At(x, y)
+
+	if fb.gammaCorrection {
+		c.X = LinearToGamma(c.X)
+		c.Y = LinearToGamma(c.Y)
+		c.Z = LinearToGamma(c.Z)
+	}
+
+	intensity := NewInterval(0, 1)
+
+	return NewColor(intensity.Clamp(c.X), intensity.Clamp(c.Y), intensity.Clamp(c.Z))
+}
@@ -6,10 +6,17 @@ type HitRecord struct {
 	T         float64
 	FrontFace bool
 	Mat       Material // Used starting from image 13
+	U         float64  // Surface coordinates of the hit point, used for texture mapping
+	V         float64
+	Tangent   Vec3    // Unit vector along the direction in which U grows, perpendicular to the normal
+	Bitangent Vec3    // Completes the tangent frame: Normal.Cross(Tangent)
+	BaryU     float64 // Barycentric coordinates of the hit point on a triangle: the point is (1-BaryU-BaryV)*v0 + BaryU*v1 + BaryV*v2
+	BaryV     float64
 }
 
 type Hittable interface {
 	Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool
+	BoundingBox() AABB // Returns a box that encloses the object, used to build a bounding volume hierarchy
 }
 
 // A normal to an object surface may point outwards or inwards... how do we choose?
@@ -32,3 +39,18 @@ func (h *HitRecord) SetFaceNormal(ray Ray, outwardNormal Vec3) {
 		h.Normal = outwardNormal.Negate()
 	}
 }
+
+// Sets the tangent frame of the surface from the derivative of the hit point with respect to U, which doesn't need to
+// be perpendicular to the normal or have unit length.
+// Note: it must be called after the normal has been set
+func (h *HitRecord) SetTangentFrame(dpdu Vec3) {
+	tangent := dpdu.Sub(h.Normal.Mul(h.Normal.Dot(dpdu)))
+
+	// The derivative vanishes at singular points, e.g. the poles of a sphere, where any tangent will do
+	if tangent.LengthSquared() <= 1e-16*dpdu.LengthSquared() {
+		tangent, _ = orthonormalBasis(h.Normal)
+	}
+
+	h.Tangent = tangent.UnitVector()
+	h.Bitangent = h.Normal.Cross(h.Tangent)
+}
@@ -30,3 +30,12 @@ func (hl HittableList) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bo
 
 	return hitAnything
 }
+
+func (hl HittableList) BoundingBox() AABB {
+	box := EmptyAABB
+	for _, object := range hl.objects {
+		box = NewSurroundingAABB(box, object.BoundingBox())
+	}
+
+	return box
+}
@@ -1,17 +1,11 @@
 package main
 
-import (
-	"fmt"
-	"io"
-)
-
-func Image1(w io.Writer) {
+func Image1() *Framebuffer {
 	width := 256
 	height := 256
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", width, height)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(width, height)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < height; y++ {
 		for x := 0; x < width; x++ {
@@ -19,14 +13,9 @@ func Image1(w io.Writer) {
 			g := float64(y) / float64(height-1)
 			b := 0.0
 
-			ir := int(255.999 * r)
-			ig := int(255.999 * g)
-			ib := int(255.999 * b)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, NewColor(r, g, b))
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image10(w io.Writer) {
+func Image10() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -13,5 +9,5 @@ func Image10(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderWithLambertianMaterial(w, world, 100, 50)
+	return cam.RenderWithLambertianMaterial(world, 100, 50)
 }
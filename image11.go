@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image11(w io.Writer) {
+func Image11() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -13,5 +9,5 @@ func Image11(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderGamut(w, world, 100, 50, false) // Render a gamut test image without gamma correction
+	return cam.RenderGamut(world, 100, 50, false) // Render a gamut test image without gamma correction
 }
@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image12(w io.Writer) {
+func Image12() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -13,5 +9,5 @@ func Image12(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderGamut(w, world, 100, 50, true) // Render a gamut test image, this time with gamma correction
+	return cam.RenderGamut(world, 100, 50, true) // Render a gamut test image, this time with gamma correction
 }
@@ -1,11 +1,7 @@
 package main
 
-import (
-	"io"
-)
-
 // This image is my attempt to simulate the bug mentioned by the book author
-func Image15(w io.Writer) {
+func Image15() *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))
@@ -21,5 +17,5 @@ func Image15(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderWithObjectMaterial(w, world, 100, 10)
+	return cam.RenderWithObjectMaterial(world, 100, 10)
 }
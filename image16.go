@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image16(w io.Writer) {
+func Image16() *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))
@@ -20,5 +16,5 @@ func Image16(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderWithObjectMaterial(w, world, 100, 50)
+	return cam.RenderWithObjectMaterial(world, 100, 50)
 }
@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image18(w io.Writer) {
+func Image18() *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))
@@ -21,5 +17,5 @@ func Image18(w io.Writer) {
 	cam := NewCamera()
 	cam.HitRayTmin = 0.001 // Avoid casting rays that start too close to a surface
 
-	cam.RenderWithObjectMaterial(w, world, 100, 50)
+	return cam.RenderWithObjectMaterial(world, 100, 50)
 }
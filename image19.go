@@ -1,11 +1,8 @@
 package main
 
-import (
-	"io"
-	"math"
-)
+import "math"
 
-func Image19(w io.Writer) {
+func Image19(options RenderOptions) *Framebuffer {
 	world := NewHittableList()
 
 	R := math.Cos(math.Pi / 4)
@@ -18,6 +15,10 @@ func Image19(w io.Writer) {
 
 	cam := NewPositionableCamera()
 	cam.SetVerticalFieldOfView(90)
+	cam.SetSamplesPerPixel(100)
+	cam.SetMaxRayDepth(50)
 
-	cam.Render(w, world, 100, 50)
+	options.Apply(&cam)
+
+	return cam.Render(world)
 }
@@ -1,17 +1,12 @@
 package main
 
-import (
-	"fmt"
-	"io"
-)
-
 func i2_rayColor(ray Ray) Color {
 	unitDirection := ray.Direction().UnitVector() // All vector components are now between -1 and +1
 	alpha := (unitDirection.Y + 1) / 2            // Alpha is a number between 0 and 1 proportional to the Y component of the ray direction
 	return NewColor(1, 1, 1).Mul(1 - alpha).Add(NewColor(0.5, 0.7, 1.0).Mul(alpha))
 }
 
-func Image2(w io.Writer) {
+func Image2() *Framebuffer {
 	// The camera is centered at (0,0,0) and oriented so that the Y-axis goes up, the X-axis goes right and the negative Z-axis points in the view direction
 	cameraCenter := NewPoint3(0, 0, 0)
 
@@ -30,9 +25,8 @@ func Image2(w io.Writer) {
 	pixelUpperLeft := viewportUpperLeft.Add(pixelDelta_U.Mul(0.5)).Add(pixelDelta_V.Mul(0.5))
 
 	// Render
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", ImageWidth, ImageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(ImageWidth, ImageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < ImageHeight; y++ {
 		for x := 0; x < ImageWidth; x++ {
@@ -41,15 +35,9 @@ func Image2(w io.Writer) {
 			ray := NewRay(cameraCenter, direction)
 			c := i2_rayColor(ray)
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
-
+	return fb
 }
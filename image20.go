@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image20(w io.Writer) {
+func Image20(options RenderOptions) *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))
@@ -20,6 +16,10 @@ func Image20(w io.Writer) {
 
 	cam := NewPositionableCamera()
 	cam.SetLookFrom(NewPoint3(-2, 2, 1))
+	cam.SetSamplesPerPixel(100)
+	cam.SetMaxRayDepth(50)
+
+	options.Apply(&cam)
 
-	cam.Render(w, world, 100, 50)
+	return cam.Render(world)
 }
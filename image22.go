@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image22(w io.Writer) {
+func Image22(options RenderOptions) *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))
@@ -23,6 +19,10 @@ func Image22(w io.Writer) {
 	cam.SetVerticalFieldOfView(20)
 	cam.SetFocusDistance(3.4)
 	cam.SetDefocusAngle(10)
+	cam.SetSamplesPerPixel(100)
+	cam.SetMaxRayDepth(50)
+
+	options.Apply(&cam)
 
-	cam.Render(w, world, 100, 50)
+	return cam.Render(world)
 }
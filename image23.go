@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image23(w io.Writer) {
+func Image23(options RenderOptions) *Framebuffer {
 	world := NewHittableList()
 
 	materialGround := NewLambertianMaterial(NewColor(0.5, 0.5, 0.5))
@@ -51,17 +47,13 @@ func Image23(w io.Writer) {
 	cam.SetFocusDistance(10)
 	cam.SetDefocusAngle(0.6)
 
-	samplesPerPixel := 50
-	maxRayDepth := 10
-
-	// Renders the final image in the book at full resolution and quality but takes a _very_ long time!
-	finalImage := false
+	cam.SetSamplesPerPixel(50)
+	cam.SetMaxRayDepth(10)
 
-	if finalImage {
-		samplesPerPixel = 500
-		maxRayDepth = 50
-		cam.SetImageWidth(1200)
-	}
+	// The final image in the book is rendered at full resolution and quality with "-width 1200 -spp 500 -depth 50",
+	// but it takes a _very_ long time!
+	options.Apply(&cam)
 
-	cam.Render(w, world, samplesPerPixel, maxRayDepth)
+	// The scene contains hundreds of spheres, a bounding volume hierarchy avoids testing most of them for every ray
+	return cam.Render(NewBVHNode(world))
 }
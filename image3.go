@@ -1,10 +1,5 @@
 package main
 
-import (
-	"fmt"
-	"io"
-)
-
 // To see if a ray hits a sphere we first need to consider the equation that describes all points on a sphere:
 // (x - center_x)^2 + (y - center_y)^2 + (z - center_z)^2 = radius^2
 // If P is a generic point we can rewrite above using the dot product:
@@ -33,7 +28,7 @@ func i3_rayColor(ray Ray) Color {
 	return i2_rayColor(ray) // Reuse gradient background from image 2
 }
 
-func Image3(w io.Writer) {
+func Image3() *Framebuffer {
 	// The camera is centered at (0,0,0) and oriented so that the Y-axis goes up, the X-axis goes right and the negative Z-axis points in the view direction
 	cameraCenter := NewPoint3(0, 0, 0)
 
@@ -52,9 +47,8 @@ func Image3(w io.Writer) {
 	pixelUpperLeft := viewportUpperLeft.Add(pixelDelta_U.Mul(0.5)).Add(pixelDelta_V.Mul(0.5))
 
 	// Render
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", ImageWidth, ImageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(ImageWidth, ImageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < ImageHeight; y++ {
 		for x := 0; x < ImageWidth; x++ {
@@ -63,15 +57,9 @@ func Image3(w io.Writer) {
 			ray := NewRay(cameraCenter, direction)
 			c := i3_rayColor(ray)
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
-
+	return fb
 }
@@ -1,10 +1,6 @@
 package main
 
-import (
-	"fmt"
-	"io"
-	"math"
-)
+import "math"
 
 func i5_rayColor(ray Ray, world HittableList) Color {
 	rec := HitRecord{}
@@ -16,7 +12,7 @@ func i5_rayColor(ray Ray, world HittableList) Color {
 	return i2_rayColor(ray) // Reuse gradient background from image 2
 }
 
-func Image5(w io.Writer) {
+func Image5() *Framebuffer {
 	// The camera is centered at (0,0,0) and oriented so that the Y-axis goes up, the X-axis goes right and the negative Z-axis points in the view direction
 	cameraCenter := NewPoint3(0, 0, 0)
 
@@ -41,9 +37,8 @@ func Image5(w io.Writer) {
 	world.Add(NewSphere(NewPoint3(0, -100.5, -1), 100))
 
 	// Render
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", ImageWidth, ImageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	fb := NewFramebuffer(ImageWidth, ImageHeight)
+	fb.DisableGammaCorrection()
 
 	for y := 0; y < ImageHeight; y++ {
 		for x := 0; x < ImageWidth; x++ {
@@ -52,14 +47,9 @@ func Image5(w io.Writer) {
 			ray := NewRay(cameraCenter, direction)
 			c := i5_rayColor(ray, world)
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
-
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+			fb.Set(x, y, c)
 		}
-		fmt.Fprintln(w)
 	}
 
-	fmt.Fprintln(w)
+	return fb
 }
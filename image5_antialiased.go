@@ -1,11 +1,7 @@
 package main
 
-import (
-	"io"
-)
-
 // Most of the code from Image5() is now moved into the Camera class
-func Image5_Refactored(w io.Writer) {
+func Image5_Refactored() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -13,5 +9,5 @@ func Image5_Refactored(w io.Writer) {
 
 	cam := NewCamera()
 
-	cam.Render(w, world)
+	return cam.Render(world)
 }
@@ -1,11 +1,7 @@
 package main
 
-import (
-	"io"
-)
-
 // Most of the code from Image5() is now moved into the Camera class
-func Image6(w io.Writer) {
+func Image6() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -13,5 +9,5 @@ func Image6(w io.Writer) {
 
 	cam := NewCamera()
 
-	cam.RenderWithMultipleSamples(w, world, 100)
+	return cam.RenderWithMultipleSamples(world, 100)
 }
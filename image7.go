@@ -1,11 +1,8 @@
 package main
 
-import (
-	"io"
-	"math"
-)
+import "math"
 
-func Image7(w io.Writer) {
+func Image7() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -14,5 +11,5 @@ func Image7(w io.Writer) {
 	cam := NewCamera()
 
 	// To avoid too much refactoring, we use the same code of Image8 but set the maxDepth to math.MaxInt (i.e. practically limitless)
-	cam.RenderWithDiffuseMaterial(w, world, 100, math.MaxInt)
+	return cam.RenderWithDiffuseMaterial(world, 100, math.MaxInt)
 }
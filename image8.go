@@ -1,10 +1,6 @@
 package main
 
-import (
-	"io"
-)
-
-func Image8(w io.Writer) {
+func Image8() *Framebuffer {
 	world := NewHittableList()
 
 	world.Add(NewSphere(NewPoint3(0, 0, -1), 0.5))
@@ -12,5 +8,5 @@ func Image8(w io.Writer) {
 
 	cam := NewCamera()
 
-	cam.RenderWithDiffuseMaterial(w, world, 100, 50)
+	return cam.RenderWithDiffuseMaterial(world, 100, 50)
 }
@@ -0,0 +1,433 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Register the JPEG decoder
+	_ "image/png"  // Register the PNG decoder
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Loads an image file into a framebuffer, converting its colors to linear values.
+// Supported formats are PNG, JPEG and PPM, plus the high dynamic range formats Radiance HDR (.hdr) and PFM (.pfm), which
+// store linear values that can go well beyond 1 (e.g. the sun in a light probe).
+func LoadImage(filename string) (*Framebuffer, error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var fb *Framebuffer
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".hdr":
+		fb, err = DecodeRadianceHDR(bufio.NewReader(f), info.Size())
+	case ".pfm":
+		fb, err = DecodePFM(bufio.NewReader(f), info.Size())
+	case ".ppm":
+		fb, err = DecodePPM(bufio.NewReader(f), info.Size())
+	default:
+		var img image.Image
+		img, _, err = image.Decode(f)
+		if err == nil {
+			fb = imageToFramebuffer(img)
+		}
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filename, err)
+	}
+
+	return fb, nil
+}
+
+// Images with more pixels are rejected, their header is more likely to be corrupt than their size real
+const maxImagePixels = 1 << 27 // A 16K x 8K light probe
+
+// Checks the size read from the header of an image before the framebuffer is allocated, so that a corrupt or hostile
+// file returns an error instead of exhausting the memory. Each row takes at least minRowSize bytes, and the whole
+// file size bytes.
+func checkImageSize(width, height int, minRowSize, size int64) error {
+	if width <= 0 || height <= 0 || width > maxImagePixels/height {
+		return fmt.Errorf("invalid image size %dx%d", width, height)
+	}
+
+	if int64(height)*minRowSize > size {
+		return fmt.Errorf("a %dx%d image doesn't fit in %d bytes", width, height, size)
+	}
+
+	return nil
+}
+
+// Images in common formats are gamma corrected, so their colors are converted back to linear values
+func imageToFramebuffer(img image.Image) *Framebuffer {
+	bounds := img.Bounds()
+	fb := NewFramebuffer(bounds.Dx(), bounds.Dy())
+
+	for y := 0; y < bounds.Dy(); y++ {
+		for x := 0; x < bounds.Dx(); x++ {
+			r, g, b, _ := img.At(bounds.Min.X+x, bounds.Min.Y+y).RGBA() // Components are in the [0, 65535] range
+
+			fb.Set(x, y, NewColor(
+				GammaToLinear(float64(r)/65535),
+				GammaToLinear(float64(g)/65535),
+				GammaToLinear(float64(b)/65535)))
+		}
+	}
+
+	return fb
+}
+
+// Reads an image in the Radiance HDR format, also known as RGBE: each pixel is stored as three 8-bit mantissas
+// sharing an 8-bit exponent. Scanlines are usually run-length encoded, one component at a time.
+// The size of the file is used to reject corrupt headers, see checkImageSize().
+func DecodeRadianceHDR(r *bufio.Reader, size int64) (*Framebuffer, error) {
+	// The header is made of text lines and ends with an empty line
+	magic, err := r.ReadString('\n')
+
+	if err != nil || !(strings.HasPrefix(magic, "#?RADIANCE") || strings.HasPrefix(magic, "#?RGBE")) {
+		return nil, errors.New("not a Radiance HDR file")
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+
+		line = strings.TrimSpace(line)
+
+		if line == "" {
+			break
+		}
+
+		if format, found := strings.CutPrefix(line, "FORMAT="); found && format != "32-bit_rle_rgbe" {
+			return nil, fmt.Errorf("unsupported format %s", format)
+		}
+	}
+
+	// Then the resolution line tells the image size and orientation, only the standard top to bottom, left to
+	// right orientation is supported
+	resolution, err := r.ReadString('\n')
+
+	if err != nil {
+		return nil, fmt.Errorf("reading resolution: %w", err)
+	}
+
+	var width, height int
+
+	if n, _ := fmt.Sscanf(resolution, "-Y %d +X %d", &height, &width); n != 2 || width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("unsupported resolution line %q", strings.TrimSpace(resolution))
+	}
+
+	// Flat scanlines take 4 bytes per pixel, encoded ones at least 2 bytes per run of up to 127 values of a component
+	minRowSize := 4 * int64(width)
+	if encoded := 4 + 4*2*int64((width+126)/127); width >= 8 && width <= 0x7fff && encoded < minRowSize {
+		minRowSize = encoded
+	}
+
+	if err := checkImageSize(width, height, minRowSize, size); err != nil {
+		return nil, err
+	}
+
+	fb := NewFramebuffer(width, height)
+	scanline := make([]byte, 4*width)
+
+	for y := 0; y < height; y++ {
+		if err := readRGBEScanline(r, scanline, width); err != nil {
+			return nil, fmt.Errorf("scanline %d: %w", y, err)
+		}
+
+		for x := 0; x < width; x++ {
+			fb.Set(x, y, rgbeToColor(scanline[4*x], scanline[4*x+1], scanline[4*x+2], scanline[4*x+3]))
+		}
+	}
+
+	return fb, nil
+}
+
+// Reads a scanline into buf, as consecutive R, G, B, E bytes
+func readRGBEScanline(r *bufio.Reader, buf []byte, width int) error {
+	start, err := r.Peek(4)
+
+	if err != nil {
+		return err
+	}
+
+	// Run-length encoded scanlines start with 2, 2 and the scanline width, everything else is stored flat
+	if width < 8 || width > 0x7fff || start[0] != 2 || start[1] != 2 || start[2]&0x80 != 0 {
+		_, err := io.ReadFull(r, buf)
+		return err
+	}
+
+	if int(start[2])<<8|int(start[3]) != width {
+		return errors.New("run-length encoded scanline width mismatch")
+	}
+
+	r.Discard(4)
+
+	// Each component is encoded separately, as a sequence of runs (a count above 128 followed by a value to repeat)
+	// and literals (a count up to 128 followed by as many values)
+	for component := 0; component < 4; component++ {
+		for x := 0; x < width; {
+			count, err := r.ReadByte()
+
+			if err != nil {
+				return err
+			}
+
+			if count > 128 {
+				n := int(count) - 128
+
+				value, err := r.ReadByte()
+
+				if err != nil {
+					return err
+				}
+
+				if x+n > width {
+					return errors.New("run goes past the end of the scanline")
+				}
+
+				for ; n > 0; n-- {
+					buf[4*x+component] = value
+					x++
+				}
+			} else {
+				n := int(count)
+
+				if n == 0 || x+n > width {
+					return errors.New("invalid literal length")
+				}
+
+				for ; n > 0; n-- {
+					value, err := r.ReadByte()
+
+					if err != nil {
+						return err
+					}
+
+					buf[4*x+component] = value
+					x++
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func rgbeToColor(r, g, b, e byte) Color {
+	if e == 0 {
+		return Color{0, 0, 0}
+	}
+
+	f := math.Ldexp(1, int(e)-(128+8)) // The mantissas are fixed point numbers in [0, 1)
+	return NewColor(float64(r)*f, float64(g)*f, float64(b)*f)
+}
+
+// Reads an image in the Portable Float Map format, see EncodePFM(). The size of the file is used to reject corrupt
+// headers, see checkImageSize().
+func DecodePFM(r *bufio.Reader, size int64) (*Framebuffer, error) {
+	// The header is made of four whitespace separated tokens, the last one is followed by a single whitespace
+	tokens := make([]string, 4)
+
+	for i := range tokens {
+		token, err := readNetpbmToken(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+
+		tokens[i] = token
+	}
+
+	channels := 0
+
+	switch tokens[0] {
+	case "PF":
+		channels = 3
+	case "Pf":
+		channels = 1 // Grayscale
+	default:
+		return nil, errors.New("not a PFM file")
+	}
+
+	width, errW := strconv.Atoi(tokens[1])
+	height, errH := strconv.Atoi(tokens[2])
+	scale, errS := strconv.ParseFloat(tokens[3], 64)
+
+	if errW != nil || errH != nil || errS != nil || width <= 0 || height <= 0 || scale == 0 {
+		return nil, fmt.Errorf("invalid header %q", strings.Join(tokens, " "))
+	}
+
+	// The sign of the scale tells the byte order
+	var order binary.ByteOrder = binary.BigEndian
+	if scale < 0 {
+		order = binary.LittleEndian
+	}
+
+	if err := checkImageSize(width, height, 4*int64(channels)*int64(width), size); err != nil {
+		return nil, err
+	}
+
+	fb := NewFramebuffer(width, height)
+	row := make([]byte, 4*channels*width)
+
+	for y := height - 1; y >= 0; y-- { // Rows are stored from bottom to top
+		if _, err := io.ReadFull(r, row); err != nil {
+			return nil, fmt.Errorf("reading pixel data: %w", err)
+		}
+
+		for x := 0; x < width; x++ {
+			var v [3]float64
+
+			for c := 0; c < channels; c++ {
+				v[c] = float64(math.Float32frombits(order.Uint32(row[4*(channels*x+c):])))
+			}
+
+			if channels == 1 {
+				v[1], v[2] = v[0], v[0]
+			}
+
+			fb.Set(x, y, NewColor(v[0], v[1], v[2]))
+		}
+	}
+
+	return fb, nil
+}
+
+// Reads a whitespace terminated token of the header of PPM and PFM files, consuming the whitespace character that
+// follows it. Comments, from a # to the end of the line, are skipped.
+func readNetpbmToken(r *bufio.Reader) (string, error) {
+	var token strings.Builder
+
+	for {
+		c, err := r.ReadByte()
+
+		if err == io.EOF && token.Len() > 0 {
+			return token.String(), nil // The last token of a plain PPM file may not be followed by a newline
+		}
+
+		if err != nil {
+			return "", err
+		}
+
+		if c == '#' && token.Len() == 0 {
+			if _, err := r.ReadString('\n'); err != nil {
+				return "", err
+			}
+			continue
+		}
+
+		isSpace := c == ' ' || c == '\t' || c == '\n' || c == '\r'
+
+		if !isSpace {
+			token.WriteByte(c)
+		} else if token.Len() > 0 {
+			return token.String(), nil
+		}
+	}
+}
+
+// Reads an image in the PPM format, either plain (P3) or binary (P6), like the ones written by EncodePlainPPM() and
+// EncodePPM(). The colors are gamma corrected, so they are converted back to linear values. The size of the file is
+// used to reject corrupt headers, see checkImageSize().
+func DecodePPM(r *bufio.Reader, size int64) (*Framebuffer, error) {
+	magic, err := readNetpbmToken(r)
+
+	if err != nil || (magic != "P3" && magic != "P6") {
+		return nil, errors.New("not a PPM file")
+	}
+
+	header := make([]int, 3) // Width, height and maximum value of a component
+
+	for i := range header {
+		token, err := readNetpbmToken(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("reading header: %w", err)
+		}
+
+		if header[i], err = strconv.Atoi(token); err != nil || header[i] <= 0 {
+			return nil, fmt.Errorf("invalid header value %q", token)
+		}
+	}
+
+	width, height, maxValue := header[0], header[1], header[2]
+
+	if maxValue > 65535 {
+		return nil, fmt.Errorf("invalid maximum value %d", maxValue)
+	}
+
+	// Reads the next component, binary components take two bytes if the maximum value doesn't fit in one
+	readComponent := func() (int, error) {
+		if magic == "P3" {
+			token, err := readNetpbmToken(r)
+			if err != nil {
+				return 0, err
+			}
+			return strconv.Atoi(token)
+		}
+
+		hi, err := r.ReadByte()
+		if err != nil || maxValue < 256 {
+			return int(hi), err
+		}
+
+		lo, err := r.ReadByte()
+		return int(hi)<<8 | int(lo), err
+	}
+
+	// Binary components take one or two bytes, plain ones at least a digit and a whitespace, except the last one
+	minRowSize := 3 * int64(width)
+	if magic == "P3" {
+		minRowSize = 6*int64(width) - 1
+	} else if maxValue >= 256 {
+		minRowSize *= 2
+	}
+
+	if err := checkImageSize(width, height, minRowSize, size); err != nil {
+		return nil, err
+	}
+
+	fb := NewFramebuffer(width, height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			var rgb [3]float64
+
+			for c := range rgb {
+				v, err := readComponent()
+
+				if err != nil {
+					return nil, fmt.Errorf("reading pixel data: %w", err)
+				}
+
+				rgb[c] = GammaToLinear(math.Min(float64(v)/float64(maxValue), 1))
+			}
+
+			fb.Set(x, y, NewColor(rgb[0], rgb[1], rgb[2]))
+		}
+	}
+
+	return fb, nil
+}
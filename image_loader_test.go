@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestDecodeRadianceHDR(t *testing.T) {
+	header := "#?RADIANCE\nFORMAT=32-bit_rle_rgbe\nEXPOSURE=1.0\n\n-Y 2 +X 8\n"
+
+	var b bytes.Buffer
+	b.WriteString(header)
+
+	// First scanline is flat: 7 pixels of about (0.5, 0.25, 1) and a black one
+	for x := 0; x < 7; x++ {
+		b.Write([]byte{128, 64, 255, 128})
+	}
+	b.Write([]byte{0, 0, 0, 0})
+
+	// Second scanline is run-length encoded: 7 pixels of (4, 2, 0) and a last one of (0.5, 0.5, 0)
+	b.Write([]byte{2, 2, 0, 8})
+	b.Write([]byte{128 + 7, 128, 1, 64})  // R: a run and a literal
+	b.Write([]byte{128 + 7, 64, 1, 64})   // G
+	b.Write([]byte{128 + 8, 0})           // B
+	b.Write([]byte{128 + 7, 131, 1, 129}) // E
+
+	fb, err := DecodeRadianceHDR(bufio.NewReader(&b), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if fb.Width() != 8 || fb.Height() != 2 {
+		t.Fatalf("got a %dx%d image, want 8x2", fb.Width(), fb.Height())
+	}
+
+	tests := []struct {
+		x, y int
+		want Color
+	}{
+		{0, 0, NewColor(0.5, 0.25, 255.0/256)},
+		{7, 0, NewColor(0, 0, 0)},
+		{3, 1, NewColor(4, 2, 0)},
+		{7, 1, NewColor(0.5, 0.5, 0)},
+	}
+
+	for _, test := range tests {
+		if got := fb.At(test.x, test.y); got.Sub(test.want).Length() > 1e-9 {
+			t.Errorf("pixel (%d, %d) = %v, want %v", test.x, test.y, got, test.want)
+		}
+	}
+}
+
+func TestDecodeRadianceHDRErrors(t *testing.T) {
+	tests := []string{
+		"P6\n",
+		"#?RADIANCE\nFORMAT=32-bit_rle_xyze\n\n-Y 1 +X 1\n\x00\x00\x00\x00",
+		"#?RADIANCE\n\n+X 1 -Y 1\n\x00\x00\x00\x00",
+		"#?RADIANCE\n\n-Y 1 +X 2\n\x00\x00\x00\x00", // Truncated
+		"#?RADIANCE\n\n-Y 1 +X 8\n\x02\x02\x00\x08\x90\x00",
+	}
+
+	for _, test := range tests {
+		if _, err := DecodeRadianceHDR(bufio.NewReader(bytes.NewBufferString(test)), int64(len(test))); err == nil {
+			t.Errorf("no error decoding %q", test)
+		}
+	}
+}
+
+func TestDecodePFMRoundTrip(t *testing.T) {
+	want := newTestFramebuffer()
+
+	var b bytes.Buffer
+	if err := EncodePFM(&b, want); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecodePFM(bufio.NewReader(&b), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got.Width() != want.Width() || got.Height() != want.Height() {
+		t.Fatalf("got a %dx%d image, want %dx%d", got.Width(), got.Height(), want.Width(), want.Height())
+	}
+
+	for y := 0; y < want.Height(); y++ {
+		for x := 0; x < want.Width(); x++ {
+			if got.At(x, y) != want.At(x, y) {
+				t.Errorf("pixel (%d, %d) = %v, want %v", x, y, got.At(x, y), want.At(x, y))
+			}
+		}
+	}
+}
+
+func TestEnvironmentBackground(t *testing.T) {
+	// A 4x2 environment: the pixel centers of the top row are at the horizon (v = 0.25 is 45 degrees up)
+	image := NewFramebuffer(4, 2)
+	for x := 0; x < 4; x++ {
+		image.Set(x, 0, NewColor(float64(x), 0, 0))
+		image.Set(x, 1, NewColor(0, 1, 0))
+	}
+
+	env := NewEnvironmentBackground(image)
+	up45 := math.Sqrt(0.5)
+
+	tests := []struct {
+		name      string
+		rotation  float64
+		intensity float64
+		direction Vec3
+		want      Color
+	}{
+		// Looking towards -Z is the center of the image, halfway between pixels 1 and 2
+		{"center", 0, 1, NewVec3(0, up45, -up45), NewColor(1.5, 0, 0)},
+		// Looking towards +Z is the left and right edges, which wrap around
+		{"wrap", 0, 1, NewVec3(0, up45, up45), NewColor(1.5, 0, 0)},
+		// The horizon is halfway between the two rows
+		{"horizon", 0, 1, NewVec3(0, 0, -1), NewColor(0.75, 0.5, 0)},
+		// A quarter turn moves the image one pixel
+		{"rotation", 90, 1, NewVec3(0, up45, -up45), NewColor(0.5, 0, 0)},
+		{"intensity", 0, 2, NewVec3(0, up45, -up45), NewColor(3, 0, 0)},
+	}
+
+	for _, test := range tests {
+		e := env
+		e.SetRotation(test.rotation)
+		e.SetIntensity(test.intensity)
+
+		if got := e.Value(NewRay(NewPoint3(0, 0, 0), test.direction)); got.Sub(test.want).Length() > 1e-9 {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestDecodePPM(t *testing.T) {
+	// The same image in plain and binary format, with comments in the header
+	plain := "P3\n# A comment\n2 1\n# Another comment\n255\n255 0 0  0 128 255"
+	binary := "P6 2 1 255\n\xff\x00\x00\x00\x80\xff"
+
+	want := []Color{NewColor(1, 0, 0), NewColor(0, GammaToLinear(128.0/255), 1)}
+
+	for _, data := range []string{plain, binary} {
+		fb, err := DecodePPM(bufio.NewReader(bytes.NewBufferString(data)), int64(len(data)))
+		if err != nil {
+			t.Fatalf("decoding %q: %v", data, err)
+		}
+
+		if fb.Width() != 2 || fb.Height() != 1 {
+			t.Fatalf("got a %dx%d image, want 2x1", fb.Width(), fb.Height())
+		}
+
+		for x, c := range want {
+			if got := fb.At(x, 0); got.Sub(c).Length() > 1e-9 {
+				t.Errorf("decoding %q: pixel %d = %v, want %v", data, x, got, c)
+			}
+		}
+	}
+
+	if _, err := DecodePPM(bufio.NewReader(bytes.NewBufferString("P6 2 1 255\n\xff\x00")), 13); err == nil {
+		t.Error("no error decoding a truncated file")
+	}
+}
+
+// Corrupt headers must be rejected before the framebuffer is allocated
+func TestDecodeImageSizeErrors(t *testing.T) {
+	tests := []struct {
+		data   string
+		decode func(*bufio.Reader, int64) (*Framebuffer, error)
+	}{
+		{"#?RADIANCE\n\n-Y 1000000 +X 1000000\n", DecodeRadianceHDR},
+		{"#?RADIANCE\n\n-Y 1000 +X 1000\n\x00\x00\x00\x00", DecodeRadianceHDR},
+		{"PF 1000000 1000000 -1.0\n", DecodePFM},
+		{"PF 1000 1000 -1.0\n\x00\x00\x00\x00", DecodePFM},
+		{"P3 1000 1000 255\n0 0 0", DecodePPM},
+	}
+
+	for _, test := range tests {
+		if _, err := test.decode(bufio.NewReader(bytes.NewBufferString(test.data)), int64(len(test.data))); err == nil || !strings.Contains(err.Error(), "image") {
+			t.Errorf("decoding %q: got error %v, want an invalid image size", test.data, err)
+		}
+	}
+}
@@ -15,6 +15,21 @@ func NewEmptyInterval() Interval {
 	return Interval{Min: math.Inf(+1), Max: math.Inf(-1)}
 }
 
+// Returns the smallest interval that contains both a and b
+func NewSurroundingInterval(a, b Interval) Interval {
+	return Interval{Min: math.Min(a.Min, b.Min), Max: math.Max(a.Max, b.Max)}
+}
+
+func (i Interval) Size() float64 {
+	return i.Max - i.Min
+}
+
+// Returns a copy of the interval padded by delta/2 on both sides
+func (i Interval) Expand(delta float64) Interval {
+	padding := delta / 2
+	return Interval{Min: i.Min - padding, Max: i.Max + padding}
+}
+
 func (i Interval) Contains(x float64) bool {
 	return i.Min <= x && x <= i.Max
 }
@@ -0,0 +1,110 @@
+package main
+
+import "math"
+
+// Objects that can be sampled as light sources implement this interface. Small lights are rarely found by rays
+// scattered at random, so the renderer also sends rays towards them explicitly (next event estimation).
+type Light interface {
+	Hittable
+
+	// Returns a random unit vector from origin towards a point of the object, and its probability density with
+	// respect to the solid angle
+	SampleDirection(rng *Rng, origin Point3) (direction Vec3, pdf float64)
+
+	// Returns the probability density of SampleDirection() picking the given direction from origin, which is zero
+	// if the direction misses the object
+	DirectionPDF(origin Point3, direction Vec3) float64
+}
+
+// Spheres are sampled over the cone of directions they cover as seen from origin
+func (s Sphere) SampleDirection(rng *Rng, origin Point3) (Vec3, float64) {
+	toCenter := s.center.Sub(origin)
+	distanceSquared := toCenter.LengthSquared()
+	radiusSquared := s.radius * s.radius
+
+	if distanceSquared <= radiusSquared {
+		return rng.UnitVec3(), 1 / (4 * math.Pi) // Inside the sphere all directions hit it
+	}
+
+	// Pick a direction uniformly within the cone around the center
+	cosThetaMax := math.Sqrt(1 - radiusSquared/distanceSquared)
+	cosTheta := 1 + rng.Double()*(cosThetaMax-1)
+	sinTheta := math.Sqrt(math.Max(0, 1-cosTheta*cosTheta))
+	phi := 2 * math.Pi * rng.Double()
+
+	w := toCenter.UnitVector()
+	t, b := orthonormalBasis(w)
+	direction := t.Mul(math.Cos(phi) * sinTheta).Add(b.Mul(math.Sin(phi) * sinTheta)).Add(w.Mul(cosTheta))
+
+	return direction, 1 / (2 * math.Pi * (1 - cosThetaMax))
+}
+
+func (s Sphere) DirectionPDF(origin Point3, direction Vec3) float64 {
+	rec := HitRecord{}
+
+	if !s.Hit(NewRay(origin, direction), 0.001, math.Inf(+1), &rec) {
+		return 0
+	}
+
+	distanceSquared := s.center.Sub(origin).LengthSquared()
+	radiusSquared := s.radius * s.radius
+
+	if distanceSquared <= radiusSquared {
+		return 1 / (4 * math.Pi)
+	}
+
+	cosThetaMax := math.Sqrt(1 - radiusSquared/distanceSquared)
+	return 1 / (2 * math.Pi * (1 - cosThetaMax))
+}
+
+// Quads are sampled uniformly over their area, which is converted to a density over the solid angle: a small area
+// dA at distance d, seen at an angle theta from its normal, covers a solid angle dA*cos(theta)/d²
+func (quad Quad) SampleDirection(rng *Rng, origin Point3) (Vec3, float64) {
+	p := quad.q.Add(quad.u.Mul(rng.Double())).Add(quad.v.Mul(rng.Double()))
+	toPoint := p.Sub(origin)
+	distanceSquared := toPoint.LengthSquared()
+	direction := toPoint.UnitVector()
+
+	return direction, quad.solidAnglePDF(direction, distanceSquared)
+}
+
+func (quad Quad) DirectionPDF(origin Point3, direction Vec3) float64 {
+	rec := HitRecord{}
+	direction = direction.UnitVector()
+
+	if !quad.Hit(NewRay(origin, direction), 0.001, math.Inf(+1), &rec) {
+		return 0
+	}
+
+	return quad.solidAnglePDF(direction, rec.T*rec.T)
+}
+
+func (quad Quad) solidAnglePDF(direction Vec3, distanceSquared float64) float64 {
+	cosine := math.Abs(direction.Dot(quad.normal))
+
+	if cosine < 1e-8 {
+		return 0 // Seen edge-on
+	}
+
+	return distanceSquared / (cosine * quad.area)
+}
+
+// Returns the object as a light if it can be sampled and its material emits light
+func emissiveLight(object Hittable) (Light, bool) {
+	var mat Material
+
+	switch o := object.(type) {
+	case Sphere:
+		mat = o.mat
+	case Quad:
+		mat = o.mat
+	default:
+		return nil, false
+	}
+
+	if _, ok := mat.(Emitter); !ok {
+		return nil, false
+	}
+
+	return object.(Light), true
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// Sampled directions must hit the light, with the density that DirectionPDF() reports, and the density must
+// integrate to one over the sphere of directions
+func TestLightPDF(t *testing.T) {
+	white := NewDiffuseLight(NewColor(1, 1, 1))
+	origin := NewPoint3(0, 0, 0)
+
+	lights := map[string]Light{
+		"sphere": NewSphereWithMaterial(NewPoint3(1, 2, -3), 0.5, white),
+		"quad":   NewQuad(NewPoint3(-1, 2, -1), NewVec3(2, 0, 0), NewVec3(0, 0.5, 1), white),
+	}
+
+	for name, light := range lights {
+		rng := NewRng(1)
+
+		for i := 0; i < 100; i++ {
+			direction, pdf := light.SampleDirection(rng, origin)
+
+			if got := light.DirectionPDF(origin, direction); math.Abs(got/pdf-1) > 1e-6 {
+				t.Errorf("%s: sampled direction %v with density %v, DirectionPDF() is %v", name, direction, pdf, got)
+			}
+		}
+
+		// Integrate with uniformly distributed directions
+		const samples = 200000
+		sum := 0.0
+
+		for i := 0; i < samples; i++ {
+			sum += light.DirectionPDF(origin, rng.UnitVec3())
+		}
+
+		if integral := sum * 4 * math.Pi / samples; math.Abs(integral-1) > 0.05 {
+			t.Errorf("%s: the density integrates to %v", name, integral)
+		}
+	}
+}
+
+func TestEmissiveLight(t *testing.T) {
+	light := NewDiffuseLight(NewColor(1, 1, 1))
+	white := NewLambertianMaterial(NewColor(1, 1, 1))
+
+	if _, ok := emissiveLight(NewSphereWithMaterial(NewPoint3(0, 0, 0), 1, light)); !ok {
+		t.Error("an emissive sphere is not a light")
+	}
+
+	if _, ok := emissiveLight(NewSphereWithMaterial(NewPoint3(0, 0, 0), 1, white)); ok {
+		t.Error("a Lambertian sphere is a light")
+	}
+
+	if _, ok := emissiveLight(NewTriangle(NewPoint3(0, 0, 0), NewPoint3(1, 0, 0), NewPoint3(0, 1, 0), light)); ok {
+		t.Error("a triangle is a light")
+	}
+}
@@ -1,49 +1,134 @@
 package main
 
 import (
+	"flag"
 	"fmt"
-	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// The output format is chosen from the file extension, see EncoderForFilename()
 const OutputFilename = "out.ppm"
 
-type Renderer func(w io.Writer)
+type Renderer func(options RenderOptions) *Framebuffer
+
+// Images rendered without a PositionableCamera have their parameters fixed in the source code
+func fixed(image func() *Framebuffer) Renderer {
+	return func(options RenderOptions) *Framebuffer {
+		if options != (RenderOptions{}) {
+			fmt.Fprintln(os.Stderr, "This image does not support rendering options, they will be ignored")
+		}
+
+		return image()
+	}
+}
 
 func main() {
-	renderers := []Renderer{Image1, Image2, Image3, Image4, Image5, Image6, Image7, Image8, Image9, Image10, Image11, Image12, Image13, Image14, Image15, Image16, Image17, Image18, Image19, Image20, Image21, Image22, Image23}
+	renderers := []Renderer{fixed(Image1), fixed(Image2), fixed(Image3), fixed(Image4), fixed(Image5), fixed(Image6), fixed(Image7), fixed(Image8), fixed(Image9), fixed(Image10), fixed(Image11), fixed(Image12), fixed(Image13), fixed(Image14), fixed(Image15), fixed(Image16), fixed(Image17), fixed(Image18), Image19, Image20, Image21, Image22, Image23, CornellBox}
 
-	imageNo := 23
+	options := RenderOptions{}
 
-	if len(os.Args) == 2 {
-		imageNo, _ = strconv.Atoi(os.Args[1])
-	} else {
-		fmt.Fprintln(os.Stderr, "No image number specified, default is", imageNo)
+	scene := flag.String("scene", "23", "number of the image to render, between 1 and "+strconv.Itoa(len(renderers))+", or a scene description file (.json)")
+	outputFilename := flag.String("o", OutputFilename, "output file, the format is chosen from the extension (.ppm, .png or .pfm)")
+	seed := flag.Int64("seed", 0, "random seed, renders with the same seed and options are identical (default is a random seed)")
+	flag.IntVar(&options.ImageWidth, "width", 0, "image width in pixels (default is the scene width)")
+	flag.IntVar(&options.SamplesPerPixel, "spp", 0, "samples per pixel (default is the scene value)")
+	flag.IntVar(&options.MaxRayDepth, "depth", 0, "maximum ray depth (default is the scene value)")
+	flag.IntVar(&options.Workers, "workers", 0, "number of rendering goroutines (default is the number of CPUs)")
+	flag.Func("aspect", "aspect ratio as a number or width:height, e.g. 16:9 (default is the scene aspect ratio)", func(s string) error {
+		ratio, err := ParseAspectRatio(s)
+		options.AspectRatio = ratio
+		return err
+	})
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: rtiow [options] [image_number|scene_file [output_file]]")
+		flag.PrintDefaults()
 	}
 
-	if imageNo < 1 || imageNo > 23 {
-		fmt.Fprintln(os.Stderr, "Image number must be between 1 and 23")
-	} else {
-		renderer := renderers[imageNo-1]
+	flag.Parse()
 
-		fmt.Fprintln(os.Stderr, "Rendering image no.", imageNo, "on file", OutputFilename)
+	// Parsing stops at the first positional argument, so flags that follow it would be silently ignored
+	for _, arg := range flag.Args() {
+		if strings.HasPrefix(arg, "-") {
+			fmt.Fprintf(os.Stderr, "Flag %s must come before the image number or scene file\n", arg)
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+
+	if flag.NArg() > 2 {
+		fmt.Fprintln(os.Stderr, "Too many arguments:", strings.Join(flag.Args()[2:], " "))
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	// Positional arguments are still accepted for compatibility with previous versions
+	if flag.NArg() >= 1 {
+		*scene = flag.Arg(0)
+	}
+
+	if flag.NArg() >= 2 {
+		*outputFilename = flag.Arg(1)
+	}
 
-		start := time.Now()
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "seed" {
+			SetRandomSeed(*seed)
+		}
+	})
 
-		f, err := os.Create(OutputFilename)
+	encoder, err := EncoderForFilename(*outputFilename)
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	var renderer Renderer
+
+	if imageNo, err := strconv.Atoi(*scene); err == nil {
+		if imageNo < 1 || imageNo > len(renderers) {
+			fmt.Fprintln(os.Stderr, "Image number must be between 1 and", len(renderers))
+			os.Exit(2)
+		}
+
+		renderer = renderers[imageNo-1]
+
+		fmt.Fprintln(os.Stderr, "Rendering image no.", imageNo, "on file", *outputFilename)
+	} else {
+		world, cam, err := LoadScene(*scene)
 
 		if err != nil {
-			panic(err)
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
 		}
 
-		defer f.Close()
+		renderer = func(options RenderOptions) *Framebuffer {
+			options.Apply(&cam)
+			return cam.Render(NewBVHNode(world))
+		}
+
+		fmt.Fprintln(os.Stderr, "Rendering scene", *scene, "on file", *outputFilename)
+	}
+
+	start := time.Now()
 
-		renderer(f)
+	f, err := os.Create(*outputFilename)
 
-		elapsed := time.Since(start)
+	if err != nil {
+		panic(err)
+	}
+
+	defer f.Close()
 
-		fmt.Fprintln(os.Stderr, "Done in", elapsed)
+	if err := encoder(f, renderer(options)); err != nil {
+		panic(err)
 	}
+
+	elapsed := time.Since(start)
+
+	fmt.Fprintln(os.Stderr, "Done in", elapsed)
 }
@@ -5,40 +5,109 @@ import "math"
 type Material interface {
 	// Returns true if the surface scattered (reflected) the incoming ray, or false if it has absorbed it.
 	// If the ray has been scattered, also returns the scattered ray and the attenuation color (which depends on the material).
-	Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool
+	// Any randomness must come from rng, as materials are shared among rendering workers.
+	Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool
+}
+
+// The result of sampling a material, see PDFMaterial
+type ScatterSample struct {
+	Direction   Vec3    // Unit vector pointing away from the surface
+	Attenuation Color   // The scattering function times the cosine of the angle with the normal, divided by the PDF
+	PDF         float64 // Probability density of the direction, with respect to the solid angle
+	Specular    bool    // The direction is not spread over a solid angle (e.g. a mirror), so it has no meaningful PDF
+}
+
+// Materials that know the probability density of the directions they scatter light to also implement this interface.
+// It lets the renderer send rays to the light sources explicitly, and weigh them against the scattered rays that
+// happen to hit a light (multiple importance sampling). Scatter() and Sample() must pick directions the same way.
+//
+// Directions are unit vectors pointing away from the hit point: wo towards the origin of the incoming ray, wi towards
+// where the light comes from. Sample() takes the incoming ray itself, like Scatter(), since materials may need more
+// than its direction, e.g. the distance it traveled inside them.
+type PDFMaterial interface {
+	Material
+
+	// Picks a random direction wi from which light is scattered towards wo, returns false if the light is absorbed
+	Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool)
+
+	// Returns the scattering function times the cosine of the angle between wi and the normal, i.e. the fraction of
+	// the light coming from wi that leaves towards wo. Specular materials return zero.
+	Eval(wo, wi Vec3, rec *HitRecord) Color
+
+	// Returns the probability density of Sample() picking wi. Specular materials return zero.
+	PDF(wo, wi Vec3, rec *HitRecord) float64
+}
+
+// Materials that emit light also implement this interface
+type Emitter interface {
+	// Returns the light emitted by the surface towards the origin of the ray
+	Emitted(ray Ray, rec *HitRecord) Color
 }
 
 type LambertianMaterial struct {
-	albedo Color
+	albedo Texture
 }
 
 type MetalMaterial struct {
-	albedo Color
+	albedo Texture
 	fuzz   float64 // If fuzz is 0 the material is perfectly smooth, setting 0 < fuzz <= 1 adds roughness to the surface
 }
 
 // Lambertian material
 func NewLambertianMaterial(a Color) LambertianMaterial {
-	return LambertianMaterial{albedo: a}
+	return NewTexturedLambertianMaterial(NewSolidColor(a))
+}
+
+// Lambertian material whose color changes across the surface
+func NewTexturedLambertianMaterial(t Texture) LambertianMaterial {
+	return LambertianMaterial{albedo: t}
 }
 
-func (m LambertianMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
-	scatterDirection := rec.Normal.Add(NewRandomUnitVec3())
+func (m LambertianMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	*scattered = NewRayWithTime(rec.P, m.scatterDirection(rng, rec), ray.Time())
+	*attenuation = m.albedo.Value(rec.U, rec.V, rec.P)
+
+	return true
+}
+
+// Directions follow a cosine distribution around the normal: adding a random unit vector to the normal gives a point on
+// a unit sphere tangent to the surface, and those points are denser where the sphere is closer to the surface.
+// Note: the direction is not normalized
+func (m LambertianMaterial) scatterDirection(rng *Rng, rec *HitRecord) Vec3 {
+	scatterDirection := rec.Normal.Add(rng.UnitVec3())
 
 	// Catch an edge case where the random unit vector is exactly opposite to the surface normal and nullifies the scatter direction
 	if scatterDirection.NearZero() {
 		scatterDirection = rec.Normal
 	}
 
-	*scattered = NewRay(rec.P, scatterDirection)
-	*attenuation = m.albedo
+	return scatterDirection
+}
 
-	return true
+func (m LambertianMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	wo := ray.Direction().UnitVector().Negate()
+	wi := m.scatterDirection(rng, rec).UnitVector()
+
+	// The scattering function is albedo/pi, so the attenuation is albedo/pi * cos(theta) / (cos(theta)/pi)
+	return ScatterSample{Direction: wi, Attenuation: m.albedo.Value(rec.U, rec.V, rec.P), PDF: m.PDF(wo, wi, rec)}, true
+}
+
+func (m LambertianMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	return m.albedo.Value(rec.U, rec.V, rec.P).Mul(math.Max(0, rec.Normal.Dot(wi)) / math.Pi)
+}
+
+func (m LambertianMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	return math.Max(0, rec.Normal.Dot(wi)) / math.Pi
 }
 
 // Metal material
 func NewMetalMaterial(a Color, fuzz float64) MetalMaterial {
-	return MetalMaterial{albedo: a, fuzz: math.Min(fuzz, 1)}
+	return NewTexturedMetalMaterial(NewSolidColor(a), fuzz)
+}
+
+// Metal material whose color changes across the surface
+func NewTexturedMetalMaterial(t Texture, fuzz float64) MetalMaterial {
+	return MetalMaterial{albedo: t, fuzz: math.Min(fuzz, 1)}
 }
 
 func Reflect(v, n Vec3) Vec3 {
@@ -52,17 +121,43 @@ func Refract(uv, n Vec3, etaiOverEtat float64) Vec3 {
 	return rOutPerp.Add(rOutParallel)
 }
 
-func (m MetalMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+func (m MetalMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
 	reflected := Reflect(ray.Direction().UnitVector(), rec.Normal)
 
-	*scattered = NewRay(rec.P, reflected.Add(NewRandomUnitVec3().Mul(m.fuzz)))
-	*attenuation = m.albedo
+	*scattered = NewRayWithTime(rec.P, reflected.Add(rng.UnitVec3().Mul(m.fuzz)), ray.Time())
+	*attenuation = m.albedo.Value(rec.U, rec.V, rec.P)
 
 	// We should just return true here, but because of the fuzziness it may happen that a ray is scattered below the surface.
 	// If that happens, just pretend the surface has absorbed it and don't scatter.
 	return rec.Normal.Dot(scattered.Direction()) > 0
 }
 
+// The fuzzy reflection has no simple density, so metals are treated as specular
+func (m MetalMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	return specularSample(m, rng, ray, rec)
+}
+
+func (m MetalMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	return Color{0, 0, 0}
+}
+
+func (m MetalMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	return 0
+}
+
+// Samples a specular material through its Scatter() method
+func specularSample(m Material, rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	scattered := Ray{}
+	sample := ScatterSample{Specular: true}
+
+	if !m.Scatter(rng, ray, rec, &sample.Attenuation, &scattered) {
+		return sample, false
+	}
+
+	sample.Direction = scattered.Direction().UnitVector()
+	return sample, true
+}
+
 // Buggy dielectric material that always refracts
 type BuggyDielectricMaterial struct {
 	ir float64
@@ -72,7 +167,7 @@ func NewBuggyDielectricMaterial(indexOfRefraction float64) BuggyDielectricMateri
 	return BuggyDielectricMaterial{ir: indexOfRefraction}
 }
 
-func (m BuggyDielectricMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+func (m BuggyDielectricMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
 	refractionRatio := m.ir
 	if rec.FrontFace {
 		refractionRatio = 1 / refractionRatio
@@ -87,7 +182,7 @@ func (m BuggyDielectricMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *C
 	refracted := rOutPerp.Add(rOutParallel)
 
 	*attenuation = Color{1, 1, 1}
-	*scattered = NewRay(rec.P, refracted)
+	*scattered = NewRayWithTime(rec.P, refracted, ray.Time())
 
 	// This adds the "weird black stuff" to the image, not sure where the original bug could have come from
 	if cosTheta < 0.3 {
@@ -106,7 +201,7 @@ func NewDielectricAlwaysRefractMaterial(indexOfRefraction float64) DielectricAlw
 	return DielectricAlwaysRefractMaterial{ir: indexOfRefraction}
 }
 
-func (m DielectricAlwaysRefractMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+func (m DielectricAlwaysRefractMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
 	refractionRatio := m.ir
 	if rec.FrontFace {
 		refractionRatio = 1 / refractionRatio
@@ -116,7 +211,7 @@ func (m DielectricAlwaysRefractMaterial) Scatter(ray Ray, rec *HitRecord, attenu
 	refracted := Refract(unitDirection, rec.Normal, refractionRatio)
 
 	*attenuation = Color{1, 1, 1}
-	*scattered = NewRay(rec.P, refracted)
+	*scattered = NewRayWithTime(rec.P, refracted, ray.Time())
 
 	return true
 }
@@ -125,6 +220,7 @@ func (m DielectricAlwaysRefractMaterial) Scatter(ray Ray, rec *HitRecord, attenu
 type DielectricMaterial struct {
 	ir             float64
 	useReflectance bool
+	absorption     Color // Absorption coefficient per unit of distance traveled inside, for each color channel
 }
 
 func NewDielectricMaterial(indexOfRefraction float64) DielectricMaterial {
@@ -143,7 +239,23 @@ func (m *DielectricMaterial) DisableReflectance() {
 	m.useReflectance = false
 }
 
-func (m DielectricMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+// Makes the material absorb light as it travels inside (Beer-Lambert law), so that thick glass is darker and more
+// saturated than thin glass. After a distance d the light is multiplied by exp(-absorption*d). The object must be
+// closed and the camera outside, as the distance is measured from the point where the ray entered.
+func (m *DielectricMaterial) SetAbsorption(absorption Color) {
+	m.absorption = absorption
+}
+
+// Returns the absorption coefficients that tint white light to the given color after the given distance
+func AbsorptionFromColor(c Color, distance float64) Color {
+	channel := func(x float64) float64 {
+		return -math.Log(math.Min(math.Max(x, 1e-6), 1)) / distance
+	}
+
+	return NewColor(channel(c.X), channel(c.Y), channel(c.Z))
+}
+
+func (m DielectricMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
 	refractionRatio := m.ir
 	if rec.FrontFace {
 		refractionRatio = 1 / refractionRatio
@@ -154,17 +266,58 @@ func (m DielectricMaterial) Scatter(ray Ray, rec *HitRecord, attenuation *Color,
 	cosTheta := rec.Normal.Dot(unitDirection.Negate())
 	sinTheta := math.Sqrt(1 - cosTheta*cosTheta)
 
-	cannotRefract := (refractionRatio*sinTheta > 1) || (m.useReflectance && SchlickReflectance(cosTheta, refractionRatio) >= RandomDouble())
+	cannotRefract := (refractionRatio*sinTheta > 1) || (m.useReflectance && SchlickReflectance(cosTheta, refractionRatio) >= rng.Double())
 
 	if cannotRefract {
 		reflected := Reflect(unitDirection, rec.Normal)
-		*scattered = NewRay(rec.P, reflected)
+		*scattered = NewRayWithTime(rec.P, reflected, ray.Time())
 	} else {
 		refracted := Refract(unitDirection, rec.Normal, refractionRatio)
-		*scattered = NewRay(rec.P, refracted)
+		*scattered = NewRayWithTime(rec.P, refracted, ray.Time())
 	}
 
 	*attenuation = Color{1, 1, 1}
 
+	// Hitting the back face means the ray has been traveling inside the material
+	if !rec.FrontFace && m.absorption != (Color{}) {
+		d := rec.T * ray.Direction().Length()
+		*attenuation = NewColor(math.Exp(-m.absorption.X*d), math.Exp(-m.absorption.Y*d), math.Exp(-m.absorption.Z*d))
+	}
+
 	return true
 }
+
+func (m DielectricMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	return specularSample(m, rng, ray, rec)
+}
+
+func (m DielectricMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	return Color{0, 0, 0}
+}
+
+func (m DielectricMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	return 0
+}
+
+// Diffuse light, i.e. a surface that emits the same light in all directions.
+// Light is only emitted from the front face, so e.g. a sphere lights its surroundings but not its inside.
+type DiffuseLight struct {
+	emit Color
+}
+
+func NewDiffuseLight(emit Color) DiffuseLight {
+	return DiffuseLight{emit: emit}
+}
+
+// A light source absorbs all the light that hits it
+func (m DiffuseLight) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	return false
+}
+
+func (m DiffuseLight) Emitted(ray Ray, rec *HitRecord) Color {
+	if !rec.FrontFace {
+		return Color{0, 0, 0}
+	}
+
+	return m.emit
+}
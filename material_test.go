@@ -0,0 +1,163 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestScatterKeepsRayTime(t *testing.T) {
+	materials := []Material{
+		NewLambertianMaterial(NewColor(0.5, 0.5, 0.5)),
+		NewMetalMaterial(NewColor(0.5, 0.5, 0.5), 0),
+		NewDielectricMaterial(1.5),
+		NewConductorMaterial(Gold, 0.3, 0.3),
+	}
+
+	rng := NewRng(1)
+	ray := NewRayWithTime(NewPoint3(0, 0, 1), NewVec3(0, 0, -1), 0.75)
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), T: 1, FrontFace: true}
+
+	for _, mat := range materials {
+		scattered, attenuation := Ray{}, Color{}
+
+		if !mat.Scatter(rng, ray, &rec, &attenuation, &scattered) {
+			t.Fatalf("%T didn't scatter the ray", mat)
+		}
+
+		if scattered.Time() != ray.Time() {
+			t.Errorf("%T scattered the ray at time %v, want %v", mat, scattered.Time(), ray.Time())
+		}
+	}
+}
+
+func TestDielectricAbsorption(t *testing.T) {
+	m := NewDielectricMaterial(1.5)
+	m.SetAbsorption(AbsorptionFromColor(NewColor(0.5, 0.25, 1), 2))
+
+	rng := NewRng(1)
+	ray := NewRay(NewPoint3(0, 0, 0), NewVec3(0, 0, -2)) // T is measured in units of the direction's length
+	scattered, attenuation := Ray{}, Color{}
+
+	// Entering the glass
+	rec := HitRecord{P: NewPoint3(0, 0, -2), Normal: NewVec3(0, 0, 1), T: 1, FrontFace: true}
+	m.Scatter(rng, ray, &rec, &attenuation, &scattered)
+
+	if attenuation != (Color{1, 1, 1}) {
+		t.Errorf("attenuation entering the glass is %v, want no absorption", attenuation)
+	}
+
+	// Leaving it after a distance of 4, i.e. twice the distance at which it has the given color
+	rec.FrontFace = false
+	rec.T = 2
+	m.Scatter(rng, ray, &rec, &attenuation, &scattered)
+
+	want := NewColor(0.25, 0.0625, 1)
+	if attenuation.Sub(want).Length() > 1e-9 {
+		t.Errorf("attenuation leaving the glass is %v, want %v", attenuation, want)
+	}
+
+	// Sampling must measure the same distance
+	if sample, _ := m.Sample(rng, ray, &rec); sample.Attenuation.Sub(want).Length() > 1e-9 {
+		t.Errorf("sampled attenuation leaving the glass is %v, want %v", sample.Attenuation, want)
+	}
+}
+
+// What checkSampling() saw of the samples of a material
+type samplingStats struct {
+	kept        int   // Samples that were not absorbed
+	specular    int   // Samples that were not spread over a solid angle, see ScatterSample
+	transmitted int   // Samples that went below the surface
+	albedo      Color // Average attenuation, absorbed samples count as black
+}
+
+// Checks that the directions sampled by a material match Eval() and PDF(), and that the density integrates to the
+// fraction of the samples that are neither absorbed nor specular
+func checkSampling(t *testing.T, m PDFMaterial, rec *HitRecord, wo Vec3) samplingStats {
+	t.Helper()
+
+	rng := NewRng(1)
+	ray := NewRay(rec.P.Add(wo), wo.Negate())
+	const samples = 200000
+	stats := samplingStats{}
+
+	for i := 0; i < samples; i++ {
+		sample, ok := m.Sample(rng, ray, rec)
+
+		if !ok {
+			continue
+		}
+
+		stats.kept++
+		stats.albedo = stats.albedo.Add(sample.Attenuation.Div(samples))
+		if sample.Direction.Dot(rec.Normal) < 0 {
+			stats.transmitted++
+		}
+
+		if sample.Specular {
+			stats.specular++
+			continue
+		}
+
+		if i%1000 != 0 {
+			continue
+		}
+
+		pdf := m.PDF(wo, sample.Direction, rec)
+		if math.Abs(pdf/sample.PDF-1) > 1e-6 {
+			t.Errorf("sampled density %v, PDF() is %v", sample.PDF, pdf)
+		}
+
+		if want := m.Eval(wo, sample.Direction, rec).Div(pdf); sample.Attenuation.Sub(want).Length() > 1e-6 {
+			t.Errorf("sampled attenuation %v, Eval()/PDF() is %v", sample.Attenuation, want)
+		}
+	}
+
+	sum := 0.0
+	for i := 0; i < samples; i++ {
+		sum += m.PDF(wo, rng.UnitVec3(), rec)
+	}
+
+	integral := sum * 4 * math.Pi / samples
+	if fraction := float64(stats.kept-stats.specular) / samples; math.Abs(integral-fraction) > 0.05 {
+		t.Errorf("the density integrates to %v, want %v", integral, fraction)
+	}
+
+	return stats
+}
+
+// Checks that a microfacet material that is smooth in one direction only samples finite, non specular directions
+func checkSmoothInOneDirection(t *testing.T, m PDFMaterial) {
+	t.Helper()
+
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), FrontFace: true}
+	rec.SetTangentFrame(NewVec3(1, 0, 0))
+	wo := NewVec3(0.5, 0.2, 0.8).UnitVector()
+	ray := NewRay(rec.P.Add(wo), wo.Negate())
+
+	finite := func(c Color, pdf float64) bool {
+		for _, x := range []float64{c.X, c.Y, c.Z, pdf} {
+			if math.IsNaN(x) || math.IsInf(x, 0) {
+				return false
+			}
+		}
+		return true
+	}
+
+	rng := NewRng(1)
+
+	for i := 0; i < 1000; i++ {
+		sample, ok := m.Sample(rng, ray, &rec)
+
+		if !ok {
+			continue
+		}
+
+		if sample.Specular {
+			t.Fatalf("%T: sampled a specular direction", m)
+		}
+
+		if !finite(sample.Attenuation, sample.PDF) || !finite(m.Eval(wo, sample.Direction, &rec), m.PDF(wo, sample.Direction, &rec)) {
+			t.Fatalf("%T: sampled attenuation %v and density %v", m, sample.Attenuation, sample.PDF)
+		}
+	}
+}
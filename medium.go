@@ -0,0 +1,133 @@
+package main
+
+import "math"
+
+// The material of smoke, fog and other participating media: light is scattered with the same probability in all
+// directions (an isotropic phase function)
+type IsotropicMaterial struct {
+	albedo Texture
+}
+
+func NewIsotropicMaterial(albedo Color) IsotropicMaterial {
+	return NewTexturedIsotropicMaterial(NewSolidColor(albedo))
+}
+
+func NewTexturedIsotropicMaterial(t Texture) IsotropicMaterial {
+	return IsotropicMaterial{albedo: t}
+}
+
+func (m IsotropicMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	*scattered = NewRayWithTime(rec.P, rng.UnitVec3(), ray.Time())
+	*attenuation = m.albedo.Value(rec.U, rec.V, rec.P)
+
+	return true
+}
+
+func (m IsotropicMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	return ScatterSample{Direction: rng.UnitVec3(), Attenuation: m.albedo.Value(rec.U, rec.V, rec.P), PDF: 1 / (4 * math.Pi)}, true
+}
+
+// There is no surface, so there is no cosine either
+func (m IsotropicMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	return m.albedo.Value(rec.U, rec.V, rec.P).Div(4 * math.Pi)
+}
+
+func (m IsotropicMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	return 1 / (4 * math.Pi)
+}
+
+// A volume of constant density, such as a puff of smoke, whose shape is given by a boundary object. Rays that enter
+// it may be scattered at any point inside, with a probability that grows with the density and the distance traveled.
+//
+// The boundary must be convex, e.g. a sphere or a box: a ray is assumed to enter and leave it only once. Distances are
+// measured along the rays the medium receives, so to move or scale it transform its boundary, not the medium itself.
+type ConstantMedium struct {
+	boundary      Hittable
+	negInvDensity float64
+	phaseFunction Material
+	seed          uint64 // Makes the random numbers of each medium independent, see rayRandom()
+}
+
+func NewConstantMedium(boundary Hittable, density float64, albedo Color) ConstantMedium {
+	return NewTexturedConstantMedium(boundary, density, NewSolidColor(albedo))
+}
+
+func NewTexturedConstantMedium(boundary Hittable, density float64, t Texture) ConstantMedium {
+	box := boundary.BoundingBox()
+	seed := hashFloats(0, box.X.Min, box.X.Max, box.Y.Min, box.Y.Max, box.Z.Min, box.Z.Max, density)
+
+	return ConstantMedium{boundary: boundary, negInvDensity: -1 / density, phaseFunction: NewTexturedIsotropicMaterial(t), seed: seed}
+}
+
+// The seed is derived from the boundary and the density, so media that share both must be given different seeds
+func (medium *ConstantMedium) SetSeed(seed uint64) {
+	medium.seed = seed
+}
+
+// Implement the Hittable interface
+func (medium ConstantMedium) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	rec1, rec2 := HitRecord{}, HitRecord{}
+
+	// Find where the ray enters and leaves the boundary, even if it starts inside
+	if !medium.boundary.Hit(ray, math.Inf(-1), math.Inf(+1), &rec1) {
+		return false
+	}
+
+	if !medium.boundary.Hit(ray, rec1.T+0.0001, math.Inf(+1), &rec2) {
+		return false
+	}
+
+	rec1.T = math.Max(rec1.T, math.Max(rayTmin, 0))
+	rec2.T = math.Min(rec2.T, rayTmax)
+
+	if rec1.T >= rec2.T {
+		return false
+	}
+
+	rayLength := ray.Direction().Length()
+	distanceInsideBoundary := (rec2.T - rec1.T) * rayLength
+	hitDistance := medium.negInvDensity * math.Log(rayRandom(ray, medium.seed))
+
+	if hitDistance > distanceInsideBoundary {
+		return false
+	}
+
+	rec.T = rec1.T + hitDistance/rayLength
+	rec.P = ray.At(rec.T)
+	rec.Normal = NewVec3(1, 0, 0) // Arbitrary, there is no surface
+	rec.FrontFace = true
+	rec.Mat = medium.phaseFunction
+	rec.U, rec.V = 0, 0
+	rec.Tangent, rec.Bitangent = Vec3{}, Vec3{}
+
+	return true
+}
+
+func (medium ConstantMedium) BoundingBox() AABB {
+	return medium.boundary.BoundingBox()
+}
+
+// Returns a random number in the interval (0, 1] that depends only on the ray and the seed. Hittables don't have a
+// random number generator, but scattered rays start from random points in random directions, so hashing them is as
+// good as drawing a new number, and keeps renders independent of the number of workers. Objects that test the same
+// ray must use different seeds, or their numbers would be the same.
+func rayRandom(ray Ray, seed uint64) float64 {
+	h := hashFloats(seed, ray.orig.X, ray.orig.Y, ray.orig.Z, ray.dir.X, ray.dir.Y, ray.dir.Z, ray.time)
+	return (float64(h>>11) + 1) / (1 << 53)
+}
+
+// Mixes the bits of the values into h
+func hashFloats(h uint64, values ...float64) uint64 {
+	h ^= 0x9e3779b97f4a7c15
+
+	for _, x := range values {
+		// SplitMix64 finalizer
+		h ^= math.Float64bits(x)
+		h += 0x9e3779b97f4a7c15
+		h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+		h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+		h ^= h >> 31
+	}
+
+	return h
+}
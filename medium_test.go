@@ -0,0 +1,178 @@
+package main
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// The fraction of rays that cross a medium without being scattered must follow the Beer-Lambert law
+func TestConstantMediumTransmittance(t *testing.T) {
+	const density = 0.5
+	medium := NewConstantMedium(NewBox(NewPoint3(0, 0, 0), NewPoint3(1, 1, 2), nil), density, NewColor(1, 1, 1))
+
+	rng := NewRng(1)
+	const rays = 100000
+	passed := 0
+
+	for i := 0; i < rays; i++ {
+		// Rays cross the box along its length of 2, starting from outside or from inside
+		origin := NewPoint3(rng.Double(), rng.Double(), -1)
+		rec := HitRecord{}
+
+		if !medium.Hit(NewRay(origin, NewVec3(0, 0, 2)), 0.001, math.Inf(+1), &rec) {
+			passed++
+		} else if rec.P.Z < 0 || rec.P.Z > 2 {
+			t.Fatalf("scattered at %v, outside the medium", rec.P)
+		}
+	}
+
+	if fraction, want := float64(passed)/rays, math.Exp(-density*2); math.Abs(fraction-want) > 0.01 {
+		t.Errorf("%v of the rays crossed the medium, want %v", fraction, want)
+	}
+}
+
+// A light seen through a fog that absorbs all the light it scatters is dimmed by the Beer-Lambert law
+func TestFogTransmittance(t *testing.T) {
+	world := NewHittableList()
+	world.Add(NewQuad(NewPoint3(-1, -1, -3), NewVec3(2, 0, 0), NewVec3(0, 2, 0), NewDiffuseLight(NewColor(1, 1, 1))))
+
+	cam := NewPositionableCamera()
+	cam.SetBackground(NewSolidBackground(NewColor(0, 0, 0)))
+	cam.SetFog(0.2, NewColor(0, 0, 0))
+
+	rng := NewRng(1)
+	const samples = 100000
+	sum := 0.0
+
+	for i := 0; i < samples; i++ {
+		sum += cam.RayColor(rng, NewRay(NewPoint3(0, 0, 0), NewVec3(0, 0, -1)), world, 10).X
+	}
+
+	if mean, want := sum/samples, math.Exp(-0.2*3); math.Abs(mean-want) > 0.01 {
+		t.Errorf("average brightness %v, want %v", mean, want)
+	}
+}
+
+// Light scattered by the fog must be the same whether the lights are sampled explicitly or not
+func TestFogLightSampling(t *testing.T) {
+	light := NewSphereWithMaterial(NewPoint3(0, 2, -2), 0.3, NewDiffuseLight(NewColor(20, 20, 20)))
+
+	world := NewHittableList()
+	world.Add(light)
+	world.Add(NewConstantMedium(NewSphere(NewPoint3(0, 0, -2), 1), 0.8, NewColor(0.8, 0.8, 0.8)))
+
+	ray := NewRay(NewPoint3(0, 0, 0), NewVec3(0, 0, -1))
+
+	// Returns the mean and variance of the brightness of the fog as seen by the ray
+	estimate := func(lights ...Light) (mean, variance float64) {
+		cam := NewPositionableCamera()
+		cam.SetBackground(NewSolidBackground(NewColor(0, 0, 0)))
+		cam.SetFog(0.05, NewColor(0.5, 0.5, 0.5))
+		cam.SetLights(lights...)
+
+		const samples = 50000
+		rng := NewRng(1)
+		sum, sumSquares := 0.0, 0.0
+
+		for i := 0; i < samples; i++ {
+			l := Luminance(cam.RayColor(rng, ray, world, 5))
+			sum += l
+			sumSquares += l * l
+		}
+
+		mean = sum / samples
+		variance = sumSquares/samples - mean*mean
+		return mean, variance / samples // Variance of the mean
+	}
+
+	scatteredMean, scatteredVariance := estimate()
+	sampledMean, sampledVariance := estimate(light)
+
+	if diff := math.Abs(scatteredMean - sampledMean); diff > 4*math.Sqrt(scatteredVariance+sampledVariance) {
+		t.Errorf("average brightness %v with light sampling, %v without", sampledMean, scatteredMean)
+	}
+
+	if sampledVariance > scatteredVariance {
+		t.Errorf("variance %v with light sampling, %v without", sampledVariance, scatteredVariance)
+	}
+}
+
+// Overlapping media must scatter independently, so that their densities add up
+func TestOverlappingMedia(t *testing.T) {
+	const density = 0.5
+	boundary := NewBox(NewPoint3(0, 0, 0), NewPoint3(1, 1, 2), nil)
+
+	other := NewConstantMedium(boundary, density, NewColor(1, 1, 1))
+	other.SetSeed(1)
+
+	world := NewHittableList()
+	world.Add(NewConstantMedium(boundary, density, NewColor(1, 1, 1)))
+	world.Add(other)
+
+	rng := NewRng(1)
+	const rays = 100000
+	passed := 0
+
+	for i := 0; i < rays; i++ {
+		origin := NewPoint3(rng.Double(), rng.Double(), -1)
+
+		if !world.Hit(NewRay(origin, NewVec3(0, 0, 2)), 0.001, math.Inf(+1), &HitRecord{}) {
+			passed++
+		}
+	}
+
+	if fraction, want := float64(passed)/rays, math.Exp(-2*density*2); math.Abs(fraction-want) > 0.01 {
+		t.Errorf("%v of the rays crossed the media, want %v", fraction, want)
+	}
+}
+
+// Scaling a medium in a scene file makes it bigger, not thinner: the density is measured in world units
+func TestScaledMediumTransmittance(t *testing.T) {
+	const density = 0.5
+	world, _, err := ReadScene(strings.NewReader(`{ "objects": [ { "type": "medium", "density": 0.5,
+		"boundary": { "type": "box", "min": [0, 0, 0], "max": [0.5, 0.5, 1] }, "transform": { "scale": [2, 2, 2] } } ] }`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rng := NewRng(1)
+	const rays = 100000
+	passed := 0
+
+	for i := 0; i < rays; i++ {
+		origin := NewPoint3(rng.Double(), rng.Double(), -1)
+		rec := HitRecord{}
+
+		if !world.Hit(NewRay(origin, NewVec3(0, 0, 1)), 0.001, math.Inf(+1), &rec) {
+			passed++
+		}
+	}
+
+	if fraction, want := float64(passed)/rays, math.Exp(-density*2); math.Abs(fraction-want) > 0.01 {
+		t.Errorf("%v of the rays crossed the medium, want %v", fraction, want)
+	}
+}
+
+// The same scene must render the same way however many media were created before it
+func TestConstantMediumIsDeterministic(t *testing.T) {
+	build := func() ConstantMedium {
+		return NewConstantMedium(NewSphere(NewPoint3(0, 0, 0), 1), 2, NewColor(1, 1, 1))
+	}
+
+	first := build()
+	NewConstantMedium(NewBox(NewPoint3(0, 0, 0), NewPoint3(1, 1, 1), nil), 1, NewColor(1, 1, 1))
+	second := build()
+
+	rng := NewRng(1)
+
+	for i := 0; i < 100; i++ {
+		ray := NewRay(NewPoint3(0, 0, -2), rng.UnitVec3().Mul(0.1).Add(NewVec3(0, 0, 1)))
+		rec1, rec2 := HitRecord{}, HitRecord{}
+
+		if hit1, hit2 := first.Hit(ray, 0.001, math.Inf(+1), &rec1), second.Hit(ray, 0.001, math.Inf(+1), &rec2); hit1 != hit2 || rec1.T != rec2.T {
+			t.Fatalf("the same medium scattered the same ray at %v and %v", rec1.T, rec2.T)
+		}
+	}
+}
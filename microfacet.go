@@ -0,0 +1,161 @@
+package main
+
+import "math"
+
+// Rough surfaces are modeled as a myriad of tiny mirrors (microfacets) whose normals are spread around the surface
+// normal according to the GGX (Trowbridge-Reitz) distribution. The roughness may differ along the tangent and the
+// bitangent, which stretches highlights like on brushed metal.
+//
+// All vectors are expressed in the local shading frame, where the normal is the Z axis, the tangent the X axis and
+// the bitangent the Y axis.
+type ggxDistribution struct {
+	alphaX float64 // Width of the distribution along the tangent
+	alphaY float64 // Width of the distribution along the bitangent
+	mirror bool    // Both widths were below ggxMinAlpha before clamping
+}
+
+// Below this width the distribution is too peaked to evaluate: narrower widths are clamped to it, and if both are
+// narrower the surface is treated as a perfect mirror
+const ggxMinAlpha = 1e-3
+
+// Maps perceptually linear roughness values in [0, 1] to the widths of the distribution
+func newGGXDistribution(roughnessU, roughnessV float64) ggxDistribution {
+	clamp := func(r float64) float64 { return math.Min(math.Max(r, 0), 1) }
+	return newGGXDistributionFromAlpha(clamp(roughnessU)*clamp(roughnessU), clamp(roughnessV)*clamp(roughnessV))
+}
+
+func newGGXDistributionFromAlpha(alphaX, alphaY float64) ggxDistribution {
+	return ggxDistribution{
+		alphaX: math.Max(alphaX, ggxMinAlpha),
+		alphaY: math.Max(alphaY, ggxMinAlpha),
+		mirror: math.Max(alphaX, alphaY) < ggxMinAlpha,
+	}
+}
+
+func (d ggxDistribution) smooth() bool {
+	return d.mirror
+}
+
+// Density of microfacet normals h, normalized so that the projected area of the microfacets is one
+func (d ggxDistribution) D(h Vec3) float64 {
+	if h.Z <= 0 {
+		return 0
+	}
+
+	e := h.X*h.X/(d.alphaX*d.alphaX) + h.Y*h.Y/(d.alphaY*d.alphaY) + h.Z*h.Z
+	return 1 / (math.Pi * d.alphaX * d.alphaY * e * e)
+}
+
+// Smith's auxiliary function: the area of microfacets hidden from direction w, relative to the visible area
+func (d ggxDistribution) lambda(w Vec3) float64 {
+	if w.Z == 0 {
+		return math.Inf(+1)
+	}
+
+	tan2 := (d.alphaX*d.alphaX*w.X*w.X + d.alphaY*d.alphaY*w.Y*w.Y) / (w.Z * w.Z)
+	return (math.Sqrt(1+tan2) - 1) / 2
+}
+
+// Fraction of the microfacets that are visible from direction w
+func (d ggxDistribution) G1(w Vec3) float64 {
+	return 1 / (1 + d.lambda(w))
+}
+
+// Fraction of the microfacets that are visible from both wo and wi
+func (d ggxDistribution) G2(wo, wi Vec3) float64 {
+	return 1 / (1 + d.lambda(wo) + d.lambda(wi))
+}
+
+// Picks a microfacet normal among the ones visible from wo, which must be above the surface, proportionally to their
+// projected area ("Sampling the GGX Distribution of Visible Normals", Heitz 2018)
+func (d ggxDistribution) sampleVisibleNormal(rng *Rng, wo Vec3) Vec3 {
+	// Stretch the view direction so that the distribution becomes a hemisphere of radius one
+	vh := NewVec3(d.alphaX*wo.X, d.alphaY*wo.Y, wo.Z).UnitVector()
+
+	t1 := NewVec3(1, 0, 0)
+	if lengthSquared := vh.X*vh.X + vh.Y*vh.Y; lengthSquared > 0 {
+		t1 = NewVec3(-vh.Y, vh.X, 0).Div(math.Sqrt(lengthSquared))
+	}
+	t2 := vh.Cross(t1)
+
+	// Pick a point on the disk seen from vh: the part of the disk hidden by the hemisphere is folded away
+	r := math.Sqrt(rng.Double())
+	phi := 2 * math.Pi * rng.Double()
+	p1 := r * math.Cos(phi)
+	p2 := r * math.Sin(phi)
+	s := (1 + vh.Z) / 2
+	p2 = (1-s)*math.Sqrt(1-p1*p1) + s*p2
+
+	// Project it onto the hemisphere and unstretch the normal
+	nh := t1.Mul(p1).Add(t2.Mul(p2)).Add(vh.Mul(math.Sqrt(math.Max(0, 1-p1*p1-p2*p2))))
+
+	return NewVec3(d.alphaX*nh.X, d.alphaY*nh.Y, math.Max(1e-6, nh.Z)).UnitVector()
+}
+
+// Probability density of sampleVisibleNormal() picking h
+func (d ggxDistribution) visibleNormalPDF(wo, h Vec3) float64 {
+	if wo.Z <= 0 {
+		return 0
+	}
+
+	return d.G1(wo) * math.Max(0, wo.Dot(h)) * d.D(h) / wo.Z
+}
+
+// Reflects wo on a visible microfacet, returns false if the reflected direction goes below the surface
+func (d ggxDistribution) sampleReflection(rng *Rng, wo Vec3) (Vec3, bool) {
+	h := d.sampleVisibleNormal(rng, wo)
+	wi := Reflect(wo.Negate(), h)
+
+	return wi, wi.Z > 0
+}
+
+// Returns D * G2 / (4 * cos(wo)), i.e. the scattering function of a perfectly reflective surface times cos(wi),
+// and the microfacet normal that reflects wo into wi
+func (d ggxDistribution) reflection(wo, wi Vec3) (float64, Vec3) {
+	if wo.Z <= 0 || wi.Z <= 0 {
+		return 0, Vec3{}
+	}
+
+	h := wo.Add(wi).UnitVector()
+	return d.D(h) * d.G2(wo, wi) / (4 * wo.Z), h
+}
+
+// Probability density of sampleReflection() picking wi: the density of the microfacet normals is converted to the
+// density of the directions, dividing it by 4 * cos(wo, h)
+func (d ggxDistribution) reflectionPDF(wo, wi Vec3) float64 {
+	if wo.Z <= 0 || wi.Z <= 0 {
+		return 0
+	}
+
+	h := wo.Add(wi).UnitVector()
+	return d.visibleNormalPDF(wo, h) / (4 * wo.Dot(h))
+}
+
+// Returns the tangent, bitangent and normal of the hit point, i.e. the axes of the local shading frame
+func shadingFrame(rec *HitRecord) (t, b, n Vec3) {
+	if rec.Tangent.NearZero() {
+		t, b = orthonormalBasis(rec.Normal)
+		return t, b, rec.Normal
+	}
+
+	return rec.Tangent, rec.Bitangent, rec.Normal
+}
+
+// Expresses a world direction in the local shading frame
+func toShadingFrame(rec *HitRecord, v Vec3) Vec3 {
+	t, b, n := shadingFrame(rec)
+	return NewVec3(v.Dot(t), v.Dot(b), v.Dot(n))
+}
+
+// Expresses the outgoing direction wo in the local shading frame. Returns false if wo is below the surface, which is
+// only possible with interpolated normals: the shading normal may lean away from a ray that hit the geometry.
+func outgoingInShadingFrame(rec *HitRecord, wo Vec3) (Vec3, bool) {
+	localWo := toShadingFrame(rec, wo)
+	return localWo, localWo.Z > 0
+}
+
+// Expresses a local direction in the world frame
+func fromShadingFrame(rec *HitRecord, v Vec3) Vec3 {
+	t, b, n := shadingFrame(rec)
+	return t.Mul(v.X).Add(b.Mul(v.Y)).Add(n.Mul(v.Z))
+}
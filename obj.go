@@ -0,0 +1,591 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Loads a mesh from a Wavefront OBJ file, returning one TriangleMesh for each group and material found in the file.
+//
+// If mat is nil, materials are read from the .mtl files referenced by the OBJ file and mapped onto the materials
+// supported by the renderer (see mtlMaterial), otherwise mat is used for the whole mesh and .mtl files are ignored.
+func LoadOBJ(filename string, mat Material) (HittableList, error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return HittableList{}, err
+	}
+
+	defer f.Close()
+
+	list, err := ReadOBJ(f, filepath.Dir(filename), mat)
+
+	var sceneError *SceneError
+	if errors.As(err, &sceneError) && sceneError.Filename == "" {
+		sceneError.Filename = filename
+	}
+
+	return list, err
+}
+
+// Reads a mesh in the Wavefront OBJ format, .mtl files are looked for in dir. See LoadOBJ() for details.
+//
+// Supported statements are vertex positions (v), normals (vn) and texture coordinates (vt), faces (f) with any
+// number of vertices and positive or negative (i.e. relative) indices, groups (g and o) and materials (mtllib and
+// usemtl). Other statements, like lines or smoothing groups, are ignored.
+func ReadOBJ(r io.Reader, dir string, mat Material) (HittableList, error) {
+	parser := objParser{dir: dir, mat: mat, builders: map[objMeshKey]*objMeshBuilder{}}
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		if err := parser.parseLine(scanner.Text()); err != nil {
+			var sceneError *SceneError
+			if errors.As(err, &sceneError) {
+				return HittableList{}, err // The error comes from a .mtl file and already has its own position
+			}
+
+			return HittableList{}, &SceneError{Line: lineNo, Err: err}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return HittableList{}, err
+	}
+
+	list := NewHittableList()
+
+	for _, key := range parser.order {
+		mesh, err := parser.builders[key].build()
+
+		if err != nil {
+			return HittableList{}, &SceneError{Field: "group " + key.group, Err: err}
+		}
+
+		list.Add(mesh)
+	}
+
+	return list, nil
+}
+
+// Triangles are collected in a separate mesh for each combination of group and material
+type objMeshKey struct {
+	group    string
+	material string
+}
+
+type objParser struct {
+	dir       string
+	mat       Material
+	positions []Point3
+	normals   []Vec3
+	texCoords []TexCoord
+	materials map[string]Material // Materials read from .mtl files
+	group     string
+	material  string
+	builders  map[objMeshKey]*objMeshBuilder
+	order     []objMeshKey // Order in which the meshes have been found in the file
+}
+
+// A vertex of a face is made of up to three indices, -1 means the index is not present
+type objVertex struct {
+	position int
+	texCoord int
+	normal   int
+}
+
+func (p *objParser) parseLine(line string) error {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+
+	fields := strings.Fields(line)
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	args := fields[1:]
+
+	switch fields[0] {
+	case "v":
+		v, err := parseFloats(args, 3, 4) // There may be a fourth (w) component, which we ignore
+		if err != nil {
+			return fmt.Errorf("vertex: %w", err)
+		}
+		p.positions = append(p.positions, NewPoint3(v[0], v[1], v[2]))
+
+	case "vn":
+		v, err := parseFloats(args, 3, 3)
+		if err != nil {
+			return fmt.Errorf("normal: %w", err)
+		}
+		p.normals = append(p.normals, NewVec3(v[0], v[1], v[2]).UnitVector())
+
+	case "vt":
+		v, err := parseFloats(args, 1, 3)
+		if err != nil {
+			return fmt.Errorf("texture coordinates: %w", err)
+		}
+		v = append(v, 0)
+		p.texCoords = append(p.texCoords, TexCoord{U: v[0], V: v[1]})
+
+	case "f":
+		return p.parseFace(args)
+
+	case "g", "o":
+		p.group = strings.Join(args, " ")
+
+	case "usemtl":
+		if len(args) != 1 {
+			return errors.New("usemtl needs a material name")
+		}
+		if p.mat == nil {
+			if _, found := p.materials[args[0]]; !found {
+				return fmt.Errorf("material %q is not defined by any mtllib", args[0])
+			}
+		}
+		p.material = args[0]
+
+	case "mtllib":
+		if p.mat != nil {
+			return nil // Materials are overridden, no need to read them
+		}
+		for _, name := range args {
+			if err := p.loadMTL(name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *objParser) parseFace(args []string) error {
+	if len(args) < 3 {
+		return fmt.Errorf("a face needs at least 3 vertices, found %d", len(args))
+	}
+
+	vertices := make([]objVertex, len(args))
+
+	for i, arg := range args {
+		indices := strings.Split(arg, "/")
+
+		if len(indices) > 3 {
+			return fmt.Errorf("invalid face vertex %q", arg)
+		}
+
+		v := objVertex{position: -1, texCoord: -1, normal: -1}
+		var err error
+
+		v.position, err = resolveOBJIndex(indices[0], len(p.positions))
+
+		if err == nil && len(indices) > 1 && indices[1] != "" {
+			v.texCoord, err = resolveOBJIndex(indices[1], len(p.texCoords))
+		}
+
+		if err == nil && len(indices) > 2 && indices[2] != "" {
+			v.normal, err = resolveOBJIndex(indices[2], len(p.normals))
+		}
+
+		if err != nil {
+			return fmt.Errorf("face vertex %q: %w", arg, err)
+		}
+
+		vertices[i] = v
+	}
+
+	key := objMeshKey{group: p.group, material: p.material}
+	builder, found := p.builders[key]
+
+	if !found {
+		builder = &objMeshBuilder{parser: p, mat: p.meshMaterial(), indexOf: map[objVertex]int{}}
+		p.builders[key] = builder
+		p.order = append(p.order, key)
+	}
+
+	polygon := make([]Point3, len(vertices))
+	for i, v := range vertices {
+		polygon[i] = p.positions[v.position]
+	}
+
+	for _, tr := range triangulatePolygon(polygon) {
+		for _, i := range tr {
+			builder.addVertex(vertices[i])
+		}
+	}
+
+	return nil
+}
+
+// OBJ indices start from 1, negative indices are relative to the end of the list read so far
+func resolveOBJIndex(s string, count int) (int, error) {
+	i, err := strconv.Atoi(s)
+
+	if err != nil {
+		return 0, fmt.Errorf("invalid index %q", s)
+	}
+
+	index := i - 1
+	if i < 0 {
+		index = count + i
+	}
+
+	if i == 0 || index < 0 || index >= count {
+		return 0, fmt.Errorf("index %d is out of range, there are %d elements", i, count)
+	}
+
+	return index, nil
+}
+
+func parseFloats(args []string, min, max int) ([]float64, error) {
+	if len(args) < min || len(args) > max {
+		if min == max {
+			return nil, fmt.Errorf("expected %d numbers, found %d", min, len(args))
+		}
+		return nil, fmt.Errorf("expected %d to %d numbers, found %d", min, max, len(args))
+	}
+
+	values := make([]float64, len(args))
+
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, fmt.Errorf("invalid number %q", arg)
+		}
+
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+func (p *objParser) meshMaterial() Material {
+	if p.mat != nil {
+		return p.mat
+	}
+
+	if mat, found := p.materials[p.material]; found {
+		return mat
+	}
+
+	return NewLambertianMaterial(NewColor(0.8, 0.8, 0.8)) // Faces without a material are light gray
+}
+
+// Collects the vertices of a mesh: OBJ faces index positions, normals and texture coordinates separately, while a
+// TriangleMesh has a single index per vertex, so every distinct combination becomes a vertex of the mesh
+type objMeshBuilder struct {
+	parser    *objParser
+	mat       Material
+	vertices  []Point3
+	normals   []Vec3
+	texCoords []TexCoord
+	indices   []int
+	indexOf   map[objVertex]int
+	noNormals bool // Set if some vertex does not have a normal
+	noUVs     bool // Set if some vertex does not have texture coordinates
+}
+
+func (b *objMeshBuilder) addVertex(v objVertex) {
+	index, found := b.indexOf[v]
+
+	if !found {
+		index = len(b.vertices)
+		b.indexOf[v] = index
+		b.vertices = append(b.vertices, b.parser.positions[v.position])
+
+		if v.normal >= 0 {
+			b.normals = append(b.normals, b.parser.normals[v.normal])
+		} else {
+			b.normals = append(b.normals, Vec3{})
+			b.noNormals = true
+		}
+
+		if v.texCoord >= 0 {
+			b.texCoords = append(b.texCoords, b.parser.texCoords[v.texCoord])
+		} else {
+			b.texCoords = append(b.texCoords, TexCoord{})
+			b.noUVs = true
+		}
+	}
+
+	b.indices = append(b.indices, index)
+}
+
+func (b *objMeshBuilder) build() (*TriangleMesh, error) {
+	// Normals and texture coordinates are only used if all vertices have them
+	normals := b.normals
+	if b.noNormals {
+		normals = nil
+	}
+
+	mesh, err := NewTriangleMesh(b.vertices, normals, b.indices, b.mat)
+
+	if err == nil && !b.noUVs {
+		err = mesh.SetTexCoords(b.texCoords)
+	}
+
+	return mesh, err
+}
+
+// Splits a polygon into triangles with the "ear clipping" method, returning the vertex indices of each triangle.
+// Unlike a simple fan of triangles, this also works for concave polygons. The polygon is assumed to be planar.
+func triangulatePolygon(polygon []Point3) [][3]int {
+	n := len(polygon)
+
+	if n == 3 {
+		return [][3]int{{0, 1, 2}}
+	}
+
+	// Compute the polygon normal with Newell's method, then project the polygon on the plane of the two axes where
+	// it is the widest, i.e. drop the axis where the normal has the largest component
+	normal := Vec3{}
+	for i := range polygon {
+		a, b := polygon[i], polygon[(i+1)%n]
+		normal = normal.Add(NewVec3((a.Y-b.Y)*(a.Z+b.Z), (a.Z-b.Z)*(a.X+b.X), (a.X-b.X)*(a.Y+b.Y)))
+	}
+
+	dropped := 0
+	if math.Abs(normal.Y) > math.Abs(normal.Axis(dropped)) {
+		dropped = 1
+	}
+	if math.Abs(normal.Z) > math.Abs(normal.Axis(dropped)) {
+		dropped = 2
+	}
+
+	xs := make([]float64, n)
+	ys := make([]float64, n)
+	for i, p := range polygon {
+		xs[i] = p.Axis((dropped + 1) % 3)
+		ys[i] = p.Axis((dropped + 2) % 3)
+	}
+
+	// Orientation of the projected polygon, so that convex vertices can be told apart from reflex ones
+	orientation := 1.0
+	if normal.Axis(dropped) < 0 {
+		orientation = -1
+	}
+
+	cross := func(a, b, c int) float64 {
+		return orientation * ((xs[b]-xs[a])*(ys[c]-ys[a]) - (ys[b]-ys[a])*(xs[c]-xs[a]))
+	}
+
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	triangles := make([][3]int, 0, n-2)
+
+	for len(remaining) > 3 {
+		m := len(remaining)
+		earFound := false
+
+		for i := 0; i < m && !earFound; i++ {
+			prev, curr, next := remaining[(i+m-1)%m], remaining[i], remaining[(i+1)%m]
+
+			if cross(prev, curr, next) <= 0 {
+				continue // Reflex (or degenerate) vertex, it cannot be the tip of an ear
+			}
+
+			// An ear must not contain any other vertex of the polygon
+			isEar := true
+			for _, j := range remaining {
+				if j != prev && j != curr && j != next && cross(prev, curr, j) >= 0 && cross(curr, next, j) >= 0 && cross(next, prev, j) >= 0 {
+					isEar = false
+					break
+				}
+			}
+
+			if isEar {
+				triangles = append(triangles, [3]int{prev, curr, next})
+				remaining = append(remaining[:i], remaining[i+1:]...)
+				earFound = true
+			}
+		}
+
+		if !earFound {
+			break // The polygon is not simple or not planar, fall back to a fan below
+		}
+	}
+
+	for i := 1; i+1 < len(remaining); i++ {
+		triangles = append(triangles, [3]int{remaining[0], remaining[i], remaining[i+1]})
+	}
+
+	return triangles
+}
+
+// Parameters of a material read from a .mtl file
+type mtlMaterial struct {
+	diffuse      Color   // Kd
+	specular     Color   // Ks
+	shininess    float64 // Ns, the exponent of the Phong model
+	ior          float64 // Ni
+	transparency float64 // 1-d or Tr
+	illum        int
+}
+
+func (p *objParser) loadMTL(name string) error {
+	filename := name
+	if !filepath.IsAbs(filename) {
+		filename = filepath.Join(p.dir, name)
+	}
+
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return fmt.Errorf("mtllib: %w", err)
+	}
+
+	defer f.Close()
+
+	materials, err := ReadMTL(f)
+
+	if err != nil {
+		var sceneError *SceneError
+		if errors.As(err, &sceneError) {
+			sceneError.Filename = filename
+		}
+		return err
+	}
+
+	if p.materials == nil {
+		p.materials = map[string]Material{}
+	}
+
+	for name, mat := range materials {
+		p.materials[name] = mat
+	}
+
+	return nil
+}
+
+// Reads materials in the .mtl format and maps them onto the materials supported by the renderer:
+//   - transparent materials (d < 1 or Tr > 0, or an illumination model with refraction) become dielectrics with
+//     index of refraction Ni;
+//   - materials with a specular color brighter than the diffuse one become metals, the Phong exponent Ns
+//     determines how fuzzy they are;
+//   - all others become Lambertian materials with the diffuse color Kd.
+func ReadMTL(r io.Reader) (map[string]Material, error) {
+	params := map[string]*mtlMaterial{}
+	var current *mtlMaterial
+
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+
+	for scanner.Scan() {
+		lineNo++
+
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "newmtl" {
+			if len(fields) != 2 {
+				return nil, &SceneError{Line: lineNo, Err: errors.New("newmtl needs a material name")}
+			}
+
+			current = &mtlMaterial{diffuse: NewColor(0.8, 0.8, 0.8), ior: 1.5}
+			params[fields[1]] = current
+
+			continue
+		}
+
+		if current == nil {
+			return nil, &SceneError{Line: lineNo, Err: fmt.Errorf("%s found before newmtl", fields[0])}
+		}
+
+		if err := current.parse(fields[0], fields[1:]); err != nil {
+			return nil, &SceneError{Line: lineNo, Err: err}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	materials := map[string]Material{}
+	for name, m := range params {
+		materials[name] = m.material()
+	}
+
+	return materials, nil
+}
+
+func (m *mtlMaterial) parse(keyword string, args []string) error {
+	switch keyword {
+	case "Kd", "Ks":
+		v, err := parseFloats(args, 3, 3)
+		if err != nil {
+			return fmt.Errorf("%s: %w", keyword, err)
+		}
+		if keyword == "Kd" {
+			m.diffuse = NewColor(v[0], v[1], v[2])
+		} else {
+			m.specular = NewColor(v[0], v[1], v[2])
+		}
+
+	case "Ns", "Ni", "d", "Tr", "illum":
+		v, err := parseFloats(args, 1, 1)
+		if err != nil {
+			return fmt.Errorf("%s: %w", keyword, err)
+		}
+		switch keyword {
+		case "Ns":
+			m.shininess = v[0]
+		case "Ni":
+			m.ior = v[0]
+		case "d":
+			m.transparency = 1 - v[0]
+		case "Tr":
+			m.transparency = v[0]
+		case "illum":
+			m.illum = int(v[0])
+		}
+	}
+
+	return nil // Other statements (e.g. texture maps) are not supported and just ignored
+}
+
+func (m *mtlMaterial) material() Material {
+	maxComponent := func(c Color) float64 {
+		return math.Max(c.X, math.Max(c.Y, c.Z))
+	}
+
+	refracts := m.illum == 4 || m.illum == 6 || m.illum == 7 || m.illum == 9
+
+	if m.transparency > 0 || refracts {
+		ior := m.ior
+		if ior <= 1 {
+			ior = 1.5 // Ni is often left at 1 (or 0) by exporters, which would make the material invisible
+		}
+		return NewDielectricMaterial(ior)
+	}
+
+	if maxComponent(m.specular) > maxComponent(m.diffuse) {
+		// The Phong exponent goes from 0 (rough) to 1000 (mirror-like), this is a common conversion to a roughness value
+		fuzz := math.Sqrt(2 / (m.shininess + 2))
+		return NewMetalMaterial(m.specular, fuzz)
+	}
+
+	return NewLambertianMaterial(m.diffuse)
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestReadOBJ(t *testing.T) {
+	obj := `
+# A square made of a single quad, plus a triangle in another group using negative indices
+v -1 -1 0
+v 1 -1 0
+v 1 1 0
+v -1 1 0
+vn 0 0 1
+f 1//1 2//1 3//1 4//1
+g other
+v 0 0 -5
+v 1 0 -5
+v 0 1 -5
+f -3 -2 -1
+`
+	list, err := ReadOBJ(strings.NewReader(obj), ".", NewLambertianMaterial(NewColor(0.5, 0.5, 0.5)))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(list.objects) != 2 {
+		t.Fatalf("got %d meshes, want 2", len(list.objects))
+	}
+
+	if n := list.objects[0].(*TriangleMesh).TriangleCount(); n != 2 {
+		t.Errorf("the quad was split into %d triangles, want 2", n)
+	}
+
+	rec := HitRecord{}
+	if !list.Hit(NewRay(NewPoint3(0.9, -0.9, 1), NewVec3(0, 0, -1)), 0, math.Inf(+1), &rec) || rec.T != 1 {
+		t.Errorf("ray should hit the quad at t=1, got %+v", rec)
+	}
+
+	if !list.Hit(NewRay(NewPoint3(0.2, 0.2, 0), NewVec3(0, 0, -1)), 0, math.Inf(+1), &rec) || rec.T != 5 {
+		t.Errorf("ray should hit the triangle at t=5, got %+v", rec)
+	}
+}
+
+func TestReadOBJErrors(t *testing.T) {
+	tests := []struct {
+		obj  string
+		line int
+		want string
+	}{
+		{"v 1 2\n", 1, "vertex: expected 3 to 4 numbers, found 2"},
+		{"v 1 2 3\nv 1 2 x\n", 2, `vertex: invalid number "x"`},
+		{"v 1 2 3\nv 1 2 3\nv 1 2 3\n\nf 1 2 4\n", 5, `face vertex "4": index 4 is out of range, there are 3 elements`},
+		{"v 1 2 3\nf 1 -2 1\n", 2, "index -2 is out of range"},
+		{"v 1 2 3\nf 0 1 1\n", 2, "index 0 is out of range"},
+		{"v 1 2 3\nf 1 1\n", 2, "a face needs at least 3 vertices"},
+		{"v 1 2 3\nf 1/2 1 1\n", 2, "index 2 is out of range, there are 0 elements"},
+		{"usemtl missing\n", 1, `material "missing" is not defined`},
+	}
+
+	for _, test := range tests {
+		_, err := ReadOBJ(strings.NewReader(test.obj), ".", nil)
+
+		var sceneError *SceneError
+		if !errors.As(err, &sceneError) || sceneError.Line != test.line || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("got error %q, want %q at line %d", err, test.want, test.line)
+		}
+	}
+}
+
+func TestReadMTL(t *testing.T) {
+	materials, err := ReadMTL(strings.NewReader(`
+newmtl matte
+Kd 0.5 0.2 0.1
+newmtl gold
+Kd 0.1 0.1 0.1
+Ks 0.9 0.7 0.3
+newmtl glass
+d 0.1
+Ni 1.33
+newmtl exported
+illum 4
+Ni 1
+`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := materials["matte"].(LambertianMaterial); !ok {
+		t.Errorf("matte should be Lambertian, got %T", materials["matte"])
+	}
+
+	if _, ok := materials["gold"].(MetalMaterial); !ok {
+		t.Errorf("gold should be metal, got %T", materials["gold"])
+	}
+
+	if m, ok := materials["glass"].(DielectricMaterial); !ok || m.ir != 1.33 {
+		t.Errorf("glass should be dielectric, got %+v", materials["glass"])
+	}
+
+	if m, ok := materials["exported"].(DielectricMaterial); !ok || m.ir != 1.5 {
+		t.Errorf("glass with Ni 1 should get the default index, got %+v", materials["exported"])
+	}
+
+	_, err = ReadMTL(strings.NewReader("Kd 1 1 1\n"))
+
+	var sceneError *SceneError
+	if !errors.As(err, &sceneError) || sceneError.Line != 1 {
+		t.Errorf("statement before newmtl should be reported at line 1, got %v", err)
+	}
+}
+
+func TestTriangulateConcavePolygon(t *testing.T) {
+	// An L-shaped polygon: a fan from the first vertex would create triangles outside of it
+	polygon := []Point3{
+		NewPoint3(0, 0, 0), NewPoint3(2, 0, 0), NewPoint3(2, 1, 0),
+		NewPoint3(1, 1, 0), NewPoint3(1, 2, 0), NewPoint3(0, 2, 0)}
+
+	triangles := triangulatePolygon(polygon)
+
+	if len(triangles) != 4 {
+		t.Fatalf("got %d triangles, want 4", len(triangles))
+	}
+
+	area := 0.0
+	for _, tr := range triangles {
+		a, b, c := polygon[tr[0]], polygon[tr[1]], polygon[tr[2]]
+		n := b.Sub(a).Cross(c.Sub(a))
+
+		if n.Z <= 0 {
+			t.Errorf("triangle %v has the wrong winding", tr)
+		}
+
+		area += n.Length() / 2
+	}
+
+	if area != 3 {
+		t.Errorf("triangles cover an area of %f, want 3", area)
+	}
+}
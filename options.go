@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Rendering options set from the command line. They are applied on top of the defaults of the scenes
+// rendered with a PositionableCamera, a zero value means the scene default is kept.
+type RenderOptions struct {
+	ImageWidth      int
+	AspectRatio     float64
+	SamplesPerPixel int
+	MaxRayDepth     int
+	Workers         int
+}
+
+func (options RenderOptions) Apply(camera *PositionableCamera) {
+	if options.ImageWidth > 0 {
+		camera.SetImageWidth(options.ImageWidth)
+	}
+
+	if options.AspectRatio > 0 {
+		camera.SetAspectRatio(options.AspectRatio)
+	}
+
+	if options.SamplesPerPixel > 0 {
+		camera.SetSamplesPerPixel(options.SamplesPerPixel)
+	}
+
+	if options.MaxRayDepth > 0 {
+		camera.SetMaxRayDepth(options.MaxRayDepth)
+	}
+
+	if options.Workers > 0 {
+		camera.SetWorkers(options.Workers)
+	}
+}
+
+// Parses an aspect ratio given either as a number (e.g. "1.5") or as a width:height pair (e.g. "16:9")
+func ParseAspectRatio(s string) (float64, error) {
+	w, h, isPair := strings.Cut(s, ":")
+
+	ratio, err := strconv.ParseFloat(w, 64)
+
+	if err == nil && isPair {
+		var d float64
+		d, err = strconv.ParseFloat(h, 64)
+		ratio /= d
+	}
+
+	if err != nil || !(ratio > 0) || ratio > 1e6 {
+		return 0, fmt.Errorf("invalid aspect ratio %q, use a positive number or width:height", s)
+	}
+
+	return ratio, nil
+}
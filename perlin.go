@@ -0,0 +1,153 @@
+package main
+
+import "math"
+
+// Builds a random permutation of the numbers 0..255, repeated twice so that indexing it with the sum of two
+// permuted values never needs wrapping around
+func newPermutation(rng *Rng) [512]int {
+	perm := [512]int{}
+
+	for i := 0; i < 256; i++ {
+		perm[i] = i
+	}
+
+	for i := 255; i > 0; i-- {
+		j := int(rng.Int63() % int64(i+1))
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+
+	copy(perm[256:], perm[:256])
+
+	return perm
+}
+
+// Perlin is a gradient noise generator, see Ken Perlin's "Improving Noise" (2002).
+// The noise is a smooth pseudo-random function of space, which is the basis of many procedural textures. The
+// pattern is fully determined by the seed, so that renders are reproducible.
+type Perlin struct {
+	perm [512]int
+}
+
+func NewPerlin(seed int64) *Perlin {
+	return &Perlin{perm: newPermutation(NewRng(seed))}
+}
+
+// Returns the noise at the point p, a value in the [-1, 1] range that changes smoothly over a distance of about 1.
+// The noise is zero at points with integer coordinates.
+func (n *Perlin) Noise(p Point3) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+
+	// Corner of the unit cube that contains the point, and position of the point inside the cube
+	X, Y, Z := int(fx)&255, int(fy)&255, int(fz)&255
+	x, y, z := p.X-fx, p.Y-fy, p.Z-fz
+
+	u, v, w := perlinFade(x), perlinFade(y), perlinFade(z)
+
+	// Hash the coordinates of the eight corners of the cube
+	perm := &n.perm
+	a := perm[X] + Y
+	aa, ab := perm[a]+Z, perm[a+1]+Z
+	b := perm[X+1] + Y
+	ba, bb := perm[b]+Z, perm[b+1]+Z
+
+	// Blend the contributions of the gradients at the corners
+	return lerp(w,
+		lerp(v,
+			lerp(u, perlinGrad(perm[aa], x, y, z), perlinGrad(perm[ba], x-1, y, z)),
+			lerp(u, perlinGrad(perm[ab], x, y-1, z), perlinGrad(perm[bb], x-1, y-1, z))),
+		lerp(v,
+			lerp(u, perlinGrad(perm[aa+1], x, y, z-1), perlinGrad(perm[ba+1], x-1, y, z-1)),
+			lerp(u, perlinGrad(perm[ab+1], x, y-1, z-1), perlinGrad(perm[bb+1], x-1, y-1, z-1))))
+}
+
+// Returns the sum of octaves of noise, each with double the frequency and half the amplitude of the previous one.
+// The absolute value of the noise is used, which gives the result a turbulent look; it's in the [0, 2] range.
+func (n *Perlin) Turbulence(p Point3, octaves int) float64 {
+	sum := 0.0
+	weight := 1.0
+
+	for i := 0; i < octaves; i++ {
+		sum += weight * math.Abs(n.Noise(p))
+		weight /= 2
+		p = p.Mul(2)
+	}
+
+	return sum
+}
+
+// Smooth interpolation curve 6t^5 - 15t^4 + 10t^3, its first and second derivatives are zero at 0 and 1
+func perlinFade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+// Returns the dot product of (x, y, z) with one of twelve gradients, pointing to the edges of a cube, chosen by hash
+func perlinGrad(hash int, x, y, z float64) float64 {
+	h := hash & 15
+
+	u := x
+	if h >= 8 {
+		u = y
+	}
+
+	v := z
+	if h < 4 {
+		v = y
+	} else if h == 12 || h == 14 {
+		v = x
+	}
+
+	if h&1 != 0 {
+		u = -u
+	}
+
+	if h&2 != 0 {
+		v = -v
+	}
+
+	return u + v
+}
+
+func lerp(t, a, b float64) float64 {
+	return a + t*(b-a)
+}
+
+// Worley is a cellular noise generator, see Steven Worley's "A Cellular Texture Basis Function" (1996).
+// Space is divided in unit cubes, each containing a random feature point; the noise is the distance to the nearest
+// feature point, which gives a pattern of cells (also known as Voronoi diagram).
+type Worley struct {
+	perm   [512]int
+	points [256]Vec3 // Positions of the feature points inside their cubes
+}
+
+func NewWorley(seed int64) *Worley {
+	rng := NewRng(seed)
+	w := &Worley{perm: newPermutation(rng)}
+
+	for i := range w.points {
+		w.points[i] = rng.Vec3()
+	}
+
+	return w
+}
+
+// Returns the distance from p to the nearest feature point, which is 0 at the center of a cell and grows towards its
+// borders. It rarely goes beyond 1.
+func (w *Worley) Noise(p Point3) float64 {
+	fx, fy, fz := math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z)
+	nearest := math.Inf(+1)
+
+	// The nearest feature point is either in the same cube as p or in one of the adjacent ones
+	for dx := -1.0; dx <= 1; dx++ {
+		for dy := -1.0; dy <= 1; dy++ {
+			for dz := -1.0; dz <= 1; dz++ {
+				corner := NewPoint3(fx+dx, fy+dy, fz+dz)
+				hash := w.perm[w.perm[w.perm[int(corner.X)&255]+int(corner.Y)&255]+int(corner.Z)&255]
+				feature := corner.Add(w.points[hash])
+
+				nearest = math.Min(nearest, feature.Sub(p).LengthSquared())
+			}
+		}
+	}
+
+	return math.Sqrt(nearest)
+}
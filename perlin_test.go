@@ -0,0 +1,62 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPerlinNoise(t *testing.T) {
+	a, b, c := NewPerlin(1), NewPerlin(1), NewPerlin(2)
+	rng := NewRng(3)
+	differ := false
+
+	for i := 0; i < 1000; i++ {
+		p := rng.Vec3InInterval(-50, 50)
+		n := a.Noise(p)
+
+		if n != b.Noise(p) {
+			t.Fatalf("same seed gave different noise at %v", p)
+		}
+
+		if n < -1 || n > 1 {
+			t.Errorf("noise at %v is %v, out of range", p, n)
+		}
+
+		if n != c.Noise(p) {
+			differ = true
+		}
+
+		// The noise is smooth
+		if d := math.Abs(a.Noise(p.Add(NewVec3(1e-4, 1e-4, 1e-4))) - n); d > 1e-3 {
+			t.Errorf("noise jumps by %v around %v", d, p)
+		}
+
+		lattice := NewPoint3(math.Floor(p.X), math.Floor(p.Y), math.Floor(p.Z))
+		if a.Noise(lattice) != 0 {
+			t.Errorf("noise at %v is not zero", lattice)
+		}
+	}
+
+	if !differ {
+		t.Error("different seeds gave the same noise")
+	}
+}
+
+func TestWorleyNoise(t *testing.T) {
+	w := NewWorley(1)
+	rng := NewRng(3)
+
+	for i := 0; i < 1000; i++ {
+		p := rng.Vec3InInterval(-50, 50)
+		n := w.Noise(p)
+
+		// A feature point in the same cube is never farther than the cube diagonal
+		if n < 0 || n > math.Sqrt(3) {
+			t.Errorf("noise at %v is %v, out of range", p, n)
+		}
+
+		if n != NewWorley(1).Noise(p) {
+			t.Fatalf("same seed gave different noise at %v", p)
+		}
+	}
+}
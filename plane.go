@@ -0,0 +1,110 @@
+package main
+
+import "math"
+
+// An infinite plane through a point, its front face is the one the normal points to.
+// The surface coordinates are distances from the point along two perpendicular directions of the plane, so they are
+// not limited to [0,1]: 3D textures such as CheckerTexture fit it better than images.
+type Plane struct {
+	point    Point3
+	normal   Vec3
+	tangent  Vec3 // Direction of U
+	binormal Vec3 // Direction of V
+	d        float64
+	mat      Material
+}
+
+func NewPlane(point Point3, normal Vec3, mat Material) Plane {
+	normal = normal.UnitVector()
+	tangent, binormal := orthonormalBasis(normal)
+
+	return Plane{point: point, normal: normal, tangent: tangent, binormal: binormal, d: normal.Dot(point), mat: mat}
+}
+
+// Implement the Hittable interface
+func (plane Plane) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	t, ok := hitPlane(ray, plane.normal, plane.d, rayTmin, rayTmax)
+
+	if !ok {
+		return false
+	}
+
+	rec.T = t
+	rec.P = ray.At(t)
+	rec.U = rec.P.Sub(plane.point).Dot(plane.tangent)
+	rec.V = rec.P.Sub(plane.point).Dot(plane.binormal)
+	rec.Mat = plane.mat
+	rec.SetFaceNormal(ray, plane.normal)
+	rec.SetTangentFrame(plane.tangent)
+
+	return true
+}
+
+// The box is infinite, except along the normal of planes perpendicular to an axis
+func (plane Plane) BoundingBox() AABB {
+	axis := func(n, p float64) Interval {
+		if math.Abs(n) == 1 {
+			return NewInterval(p, p).Expand(0.0001)
+		}
+		return Universe
+	}
+
+	return NewAABB(axis(plane.normal.X, plane.point.X), axis(plane.normal.Y, plane.point.Y), axis(plane.normal.Z, plane.point.Z))
+}
+
+// A disk is the region of a plane within a radius from the center, its front face is the one the normal points to.
+// The surface coordinates map the square enclosing the disk to [0,1]x[0,1], so an image is applied like a label.
+type Disk struct {
+	center   Point3
+	normal   Vec3
+	tangent  Vec3 // Direction of U
+	binormal Vec3 // Direction of V
+	radius   float64
+	d        float64
+	mat      Material
+}
+
+func NewDisk(center Point3, normal Vec3, radius float64, mat Material) Disk {
+	normal = normal.UnitVector()
+	tangent, binormal := orthonormalBasis(normal)
+
+	return Disk{center: center, normal: normal, tangent: tangent, binormal: binormal, radius: radius, d: normal.Dot(center), mat: mat}
+}
+
+// Implement the Hittable interface
+func (disk Disk) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	t, ok := hitPlane(ray, disk.normal, disk.d, rayTmin, rayTmax)
+
+	if !ok {
+		return false
+	}
+
+	p := ray.At(t)
+	offset := p.Sub(disk.center)
+
+	if offset.LengthSquared() > disk.radius*disk.radius {
+		return false
+	}
+
+	rec.T = t
+	rec.P = p
+	rec.U = 0.5 + offset.Dot(disk.tangent)/(2*disk.radius)
+	rec.V = 0.5 + offset.Dot(disk.binormal)/(2*disk.radius)
+	rec.Mat = disk.mat
+	rec.SetFaceNormal(ray, disk.normal)
+	rec.SetTangentFrame(disk.tangent)
+
+	return true
+}
+
+func (disk Disk) BoundingBox() AABB {
+	// Along each axis, the disk extends by the radius times the sine of the angle between the axis and the normal
+	extent := func(n float64) float64 {
+		return disk.radius * math.Sqrt(math.Max(0, 1-n*n))
+	}
+
+	e := NewVec3(extent(disk.normal.X), extent(disk.normal.Y), extent(disk.normal.Z))
+	box := NewAABBFromPoints(disk.center.Sub(e), disk.center.Add(e))
+
+	return box.Pad(0.0001)
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestPlaneHit(t *testing.T) {
+	plane := NewPlane(NewPoint3(0, -1, 0), NewVec3(0, 2, 0), nil)
+
+	rec := HitRecord{}
+	if !plane.Hit(NewRay(NewPoint3(3, 1, 4), NewVec3(1, -1, 0)), 0, math.Inf(+1), &rec) {
+		t.Fatalf("ray should hit the plane")
+	}
+
+	if rec.T != 2 || rec.P != NewPoint3(5, -1, 4) || !rec.FrontFace || rec.Normal != NewVec3(0, 1, 0) {
+		t.Errorf("got t=%v, p=%v, front face %v and normal %v", rec.T, rec.P, rec.FrontFace, rec.Normal)
+	}
+
+	// The surface coordinates are distances on the plane
+	if d := math.Hypot(rec.U, rec.V); math.Abs(d-math.Hypot(5, 4)) > 1e-9 {
+		t.Errorf("got (u, v) = (%v, %v), at a distance %v from the point", rec.U, rec.V, d)
+	}
+
+	if plane.Hit(NewRay(NewPoint3(0, 1, 0), NewVec3(0, 1, 0)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray going away from the plane should miss it")
+	}
+
+	box := plane.BoundingBox()
+	if !box.IsInfinite() || box.Y.Min > -1 || box.Y.Max < -1 || box.Y.Size() > 0.001 {
+		t.Errorf("bad bounding box %v", box)
+	}
+}
+
+func TestDiskHit(t *testing.T) {
+	disk := NewDisk(NewPoint3(0, 0, -1), NewVec3(0, 0, 1), 0.5, nil)
+	origin := NewPoint3(0, 0, 0)
+
+	rec := HitRecord{}
+	if !disk.Hit(NewRay(origin, NewVec3(0, 0, -1)), 0, math.Inf(+1), &rec) || rec.U != 0.5 || rec.V != 0.5 || !rec.FrontFace {
+		t.Errorf("ray through the center got (u, v) = (%v, %v) and front face %v", rec.U, rec.V, rec.FrontFace)
+	}
+
+	if !disk.Hit(NewRay(origin, NewVec3(0.3, 0.3, -1)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray inside the disk should hit it")
+	}
+
+	if disk.Hit(NewRay(origin, NewVec3(0.4, 0.4, -1)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray outside the disk should miss it")
+	}
+
+	box := disk.BoundingBox()
+	if box.X != NewInterval(-0.5, 0.5) || box.Y != NewInterval(-0.5, 0.5) || box.Z.Size() <= 0 {
+		t.Errorf("bad bounding box %v", box)
+	}
+}
+
+func TestBVHWithInfiniteObjects(t *testing.T) {
+	world := NewHittableList()
+	world.Add(NewPlane(NewPoint3(0, 0, 0), NewVec3(0, 1, 0), nil))
+	world.Add(NewSphere(NewPoint3(0, 1, 0), 0.5))
+	world.Add(NewSphere(NewPoint3(3, 1, 0), 0.5))
+
+	bvh := NewBVHNode(world)
+	rng := NewRng(1)
+
+	for i := 0; i < 1000; i++ {
+		ray := NewRay(rng.Vec3InInterval(-5, 5), rng.UnitVec3())
+
+		a, b := HitRecord{}, HitRecord{}
+		hitA := world.Hit(ray, 0.001, math.Inf(+1), &a)
+		hitB := bvh.Hit(ray, 0.001, math.Inf(+1), &b)
+
+		if hitA != hitB || a.T != b.T {
+			t.Fatalf("ray %v: list hit %v at %v, BVH hit %v at %v", ray, hitA, a.T, hitB, b.T)
+		}
+	}
+}
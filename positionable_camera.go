@@ -2,36 +2,53 @@ package main
 
 import (
 	"fmt"
-	"io"
 	"math"
 	"os"
+	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
 type PositionableCamera struct {
-	aspectRatio    float64
-	imageWidth     int
-	imageHeight    int
-	vfov           float64 // Vertical field of view angle in degrees
-	lookFrom       Point3  // Where the camera "eye" is positioned
-	lookAt         Point3  // Where the camera is looking at
-	vUp            Vec3    // Up direction relative to the camera
-	focusDistance  float64
-	defocusAngle   float64
-	pixelDelta_U   Vec3
-	pixelDelta_V   Vec3
-	pixelUpperLeft Point3
-	defocusDisk_U  Vec3
-	defocusDisk_V  Vec3
+	aspectRatio     float64
+	imageWidth      int
+	imageHeight     int
+	vfov            float64 // Vertical field of view angle in degrees
+	lookFrom        Point3  // Where the camera "eye" is positioned
+	lookAt          Point3  // Where the camera is looking at
+	vUp             Vec3    // Up direction relative to the camera
+	focusDistance   float64
+	defocusAngle    float64
+	pixelDelta_U    Vec3
+	pixelDelta_V    Vec3
+	pixelUpperLeft  Point3
+	defocusDisk_U   Vec3
+	defocusDisk_V   Vec3
+	workers         int        // Number of goroutines that render the image in parallel
+	samplesPerPixel int        // Number of rays cast for each pixel
+	maxRayDepth     int        // Maximum number of times a ray can bounce around the scene
+	background      Background // Color of the rays that don't hit any object
+	shutterOpen     float64    // Camera rays are cast at random times between the shutter opening and closing
+	shutterClose    float64
+	lights          []Light // Objects that emit light, which are sampled explicitly
+	fogDensity      float64 // Density of the fog that fills the whole scene, 0 for no fog
+	fog             Material
 }
 
 func NewPositionableCamera() PositionableCamera {
-	return PositionableCamera{imageWidth: ImageWidth, aspectRatio: AspectRatio, vfov: 90, lookFrom: NewPoint3(0, 0, 0), lookAt: NewPoint3(0, 0, -1), vUp: NewVec3(0, 1, 0), focusDistance: 0, defocusAngle: 0}
+	return PositionableCamera{imageWidth: ImageWidth, aspectRatio: AspectRatio, vfov: 90, lookFrom: NewPoint3(0, 0, 0), lookAt: NewPoint3(0, 0, -1), vUp: NewVec3(0, 1, 0), focusDistance: 0, defocusAngle: 0, workers: runtime.NumCPU(), samplesPerPixel: 10, maxRayDepth: 10, background: NewSkyBackground()}
 }
 
 func (camera *PositionableCamera) SetAspectRatio(ratio float64) {
 	camera.aspectRatio = ratio
 }
 
+// The default background is the gradient sky of image 2. A black background makes for a scene that is lit only by
+// emissive objects.
+func (camera *PositionableCamera) SetBackground(background Background) {
+	camera.background = background
+}
+
 func (camera *PositionableCamera) SetDefocusAngle(angle float64) {
 	camera.defocusAngle = angle
 }
@@ -40,10 +57,23 @@ func (camera *PositionableCamera) SetFocusDistance(distance float64) {
 	camera.focusDistance = distance
 }
 
+// Fills the whole scene with a homogeneous fog, which scatters light like a ConstantMedium. Light from the background
+// never gets through the fog, so it suits scenes lit by lights; a ConstantMedium can be used to fog a smaller region.
+func (camera *PositionableCamera) SetFog(density float64, albedo Color) {
+	camera.fogDensity = density
+	camera.fog = NewIsotropicMaterial(albedo)
+}
+
 func (camera *PositionableCamera) SetImageWidth(width int) {
 	camera.imageWidth = width
 }
 
+// Lights are rendered even if they are not set here, but small ones will be very noisy: the objects set here are
+// sampled explicitly, as are their materials. They must be part of the rendered world too.
+func (camera *PositionableCamera) SetLights(lights ...Light) {
+	camera.lights = lights
+}
+
 func (camera *PositionableCamera) SetLookAt(p Point3) {
 	camera.lookAt = p
 }
@@ -52,12 +82,38 @@ func (camera *PositionableCamera) SetLookFrom(p Point3) {
 	camera.lookFrom = p
 }
 
+func (camera *PositionableCamera) SetMaxRayDepth(depth int) {
+	camera.maxRayDepth = depth
+}
+
+func (camera *PositionableCamera) SetSamplesPerPixel(samples int) {
+	camera.samplesPerPixel = samples
+}
+
+// Keeping the shutter open for some time blurs the objects that move meanwhile. By default it opens and closes at time
+// 0, which freezes the motion.
+func (camera *PositionableCamera) SetShutter(open, close float64) {
+	camera.shutterOpen = open
+	camera.shutterClose = close
+}
+
 func (camera *PositionableCamera) SetVerticalFieldOfView(vfov float64) {
 	camera.vfov = vfov
 }
 
+func (camera *PositionableCamera) SetViewUp(v Vec3) {
+	camera.vUp = v
+}
+
+func (camera *PositionableCamera) SetWorkers(workers int) {
+	camera.workers = workers
+}
+
 func (camera *PositionableCamera) Initialize() {
 	camera.imageHeight = int(float64(camera.imageWidth) / camera.aspectRatio)
+	if camera.imageHeight < 1 {
+		camera.imageHeight = 1
+	}
 
 	// Determine the viewport dimentions
 	focusDistance := camera.focusDistance
@@ -95,23 +151,23 @@ func (camera *PositionableCamera) Initialize() {
 }
 
 // Returns a random point in the square surrounding a pixel at the origin
-func (camera PositionableCamera) pixelSampleSquare() Vec3 {
+func (camera PositionableCamera) pixelSampleSquare(rng *Rng) Vec3 {
 	// Get a random point position, each coordinate is in the [-0.5, 0.5) interval
 	// (remember that pixelUpperLeft starts at x=0.5, y=0.5)
-	px := -0.5 + RandomDouble()
-	py := -0.5 + RandomDouble()
+	px := -0.5 + rng.Double()
+	py := -0.5 + rng.Double()
 
 	// Return the vector that leads the ray into the above randomized point of the viewport
 	return camera.pixelDelta_U.Mul(px).Add(camera.pixelDelta_V.Mul(py))
 }
 
-func (camera PositionableCamera) getRandomPointInDefocusDisk() Point3 {
+func (camera PositionableCamera) getRandomPointInDefocusDisk(rng *Rng) Point3 {
 	// Get a random point in the unit disk
 	var x, y float64
 
 	for {
-		x = RandomDoubleInInterval(-1, 1)
-		y = RandomDoubleInInterval(-1, 1)
+		x = rng.DoubleInInterval(-1, 1)
+		y = rng.DoubleInInterval(-1, 1)
 		if x*x+y*y <= 1 {
 			break
 		}
@@ -122,76 +178,261 @@ func (camera PositionableCamera) getRandomPointInDefocusDisk() Point3 {
 }
 
 // Get a randomly sampled camera ray for the pixel at location i, j
-func (camera PositionableCamera) getRay(i, j int) Ray {
+func (camera PositionableCamera) getRay(rng *Rng, i, j int) Ray {
 	pixelCenter := camera.pixelUpperLeft.Add(camera.pixelDelta_U.Mul(float64(i))).Add(camera.pixelDelta_V.Mul(float64(j)))
-	pixelSample := pixelCenter.Add(camera.pixelSampleSquare())
+	pixelSample := pixelCenter.Add(camera.pixelSampleSquare(rng))
 	origin := camera.lookFrom
 	if camera.defocusAngle > 0 {
-		origin = camera.getRandomPointInDefocusDisk()
+		origin = camera.getRandomPointInDefocusDisk(rng)
 	}
 	direction := pixelSample.Sub(origin) // Note: the direction is not normalized
 
-	return NewRay(origin, direction)
+	time := camera.shutterOpen
+	if camera.shutterClose != camera.shutterOpen {
+		time = rng.DoubleInInterval(camera.shutterOpen, camera.shutterClose)
+	}
+
+	return NewRayWithTime(origin, direction, time)
 }
 
 // The following function uses the properties of the object material to properly compute the ray color
-func (camera PositionableCamera) RayColor(ray Ray, world Hittable, depth int) Color {
+func (camera PositionableCamera) RayColor(rng *Rng, ray Ray, world Hittable, depth int) Color {
+	return camera.rayColor(rng, ray, world, depth, 0)
+}
+
+// Traces a ray through the scene. If the ray has been scattered by a surface where the lights and the background have
+// also been sampled explicitly, scatteredPDF is the probability density of the scattered direction, otherwise it is zero.
+func (camera PositionableCamera) rayColor(rng *Rng, ray Ray, world Hittable, depth int, scatteredPDF float64) Color {
 	rec := HitRecord{}
 
 	if depth <= 0 {
 		return Color{0, 0, 0}
 	}
 
-	if world.Hit(ray, 0.001, math.Inf(+1), &rec) {
+	background, isSampledBackground := camera.background.(SampledBackground)
+	hit := world.Hit(ray, 0.001, math.Inf(+1), &rec)
+
+	// In the fog, the ray may be scattered before reaching the object
+	if camera.fogDensity > 0 {
+		distance := -math.Log(1-rng.Double()) / camera.fogDensity
+		rayLength := ray.Direction().Length()
+
+		if !hit || distance < rec.T*rayLength {
+			rec = HitRecord{T: distance / rayLength, Normal: NewVec3(1, 0, 0), FrontFace: true, Mat: camera.fog}
+			rec.P = ray.At(rec.T)
+			hit = true
+		}
+	}
+
+	if hit {
 		scattered := Ray{}
 		attenuation := Color{}
 
-		if rec.Mat.Scatter(ray, &rec, &attenuation, &scattered) {
-			c := camera.RayColor(scattered, world, depth-1)
+		// Light emitted by the surface itself, if any
+		emitted := Color{0, 0, 0}
+		if emitter, ok := rec.Mat.(Emitter); ok {
+			emitted = emitter.Emitted(ray, &rec)
 
-			return Color{c.X * attenuation.X, c.Y * attenuation.Y, c.Z * attenuation.Z}
+			// The same light may have been found by sampling the lights, the contributions of both strategies are
+			// weighted so that they add up correctly (multiple importance sampling)
+			if scatteredPDF > 0 && len(camera.lights) > 0 {
+				emitted = emitted.Mul(powerHeuristic(scatteredPDF, camera.lightsPDF(ray.Origin(), ray.Direction())))
+			}
 		}
 
+		if rec.Mat.Scatter(rng, ray, &rec, &attenuation, &scattered) {
+			// On non-specular surfaces, the light coming directly from the lights and the background is sampled
+			// explicitly too. This is skipped on the last bounce, where the scattered ray couldn't reach them anyway.
+			direct := Color{0, 0, 0}
+			pdf := 0.0
+
+			if mat, ok := rec.Mat.(PDFMaterial); ok && depth > 1 && (len(camera.lights) > 0 || isSampledBackground) {
+				wo := ray.Direction().UnitVector().Negate()
+				pdf = mat.PDF(wo, scattered.Direction().UnitVector(), &rec)
+
+				if pdf > 0 {
+					direct = camera.sampleLights(rng, mat, world, ray, &rec)
+
+					if isSampledBackground {
+						direct = direct.Add(camera.sampleBackground(rng, background, mat, world, ray, &rec))
+					}
+				}
+			}
+
+			c := camera.rayColor(rng, scattered, world, depth-1, pdf)
+
+			return emitted.Add(direct).Add(Color{c.X * attenuation.X, c.Y * attenuation.Y, c.Z * attenuation.Z})
+		}
+
+		return emitted
+	}
+
+	c := camera.background.Value(ray)
+
+	// The same light may have been found by sampling the background, see above
+	if isSampledBackground && scatteredPDF > 0 {
+		c = c.Mul(powerHeuristic(scatteredPDF, background.PDF(ray.Direction())))
+	}
+
+	return c
+}
+
+// Returns the light that reaches the hit point, and is scattered along the incoming ray, from a random point of one of
+// the lights, unless some object is in the way
+func (camera PositionableCamera) sampleLights(rng *Rng, mat PDFMaterial, world Hittable, ray Ray, rec *HitRecord) Color {
+	if len(camera.lights) == 0 {
+		return Color{0, 0, 0}
+	}
+
+	light := camera.lights[clampInt(int(rng.Double()*float64(len(camera.lights))), 0, len(camera.lights)-1)]
+	direction, pdf := light.SampleDirection(rng, rec.P)
+
+	if pdf <= 0 {
 		return Color{0, 0, 0}
 	}
 
-	return i2_rayColor(ray) // Reuse gradient background from image 2
+	wo := ray.Direction().UnitVector().Negate()
+	f := mat.Eval(wo, direction, rec)
+
+	if f.NearZero() {
+		return Color{0, 0, 0} // E.g. the light is below the surface
+	}
+
+	// The light is actually reached if the first object along the way emits light towards the hit point
+	shadowRay := NewRayWithTime(rec.P, direction, ray.Time())
+	lightRec := HitRecord{}
+
+	if !world.Hit(shadowRay, 0.001, math.Inf(+1), &lightRec) {
+		return Color{0, 0, 0}
+	}
+
+	emitter, ok := lightRec.Mat.(Emitter)
+
+	if !ok {
+		return Color{0, 0, 0}
+	}
+
+	// Since any light may have been picked, the probability density of the direction is the average of all the lights
+	lightPDF := camera.lightsPDF(rec.P, direction)
+	weight := powerHeuristic(lightPDF, mat.PDF(wo, direction, rec))
+
+	direct := emitter.Emitted(shadowRay, &lightRec).MultiplyComponents(f).Mul(weight / lightPDF)
+
+	// Fraction of the light that gets through the fog, the direction is a unit vector so T is the distance
+	if camera.fogDensity > 0 {
+		direct = direct.Mul(math.Exp(-camera.fogDensity * lightRec.T))
+	}
+
+	return direct
 }
 
-func (camera *PositionableCamera) Render(w io.Writer, world Hittable, samplesPerPixel, maxRayDepth int) {
-	camera.Initialize()
+// Returns the probability density of sampleLights() picking the given direction
+func (camera PositionableCamera) lightsPDF(origin Point3, direction Vec3) float64 {
+	sum := 0.0
 
-	fmt.Fprintf(w, "P3\n") // Magic
-	fmt.Fprintf(w, "%d %d\n", camera.imageWidth, camera.imageHeight)
-	fmt.Fprintf(w, "255\n") // Maximum value of a color component
+	for _, light := range camera.lights {
+		sum += light.DirectionPDF(origin, direction)
+	}
 
-	for y := 0; y < camera.imageHeight; y++ {
-		fmt.Fprintf(os.Stderr, "Rendering scanline %d of %d (%d%%)\n", y+1, camera.imageHeight, (y+1)*100/camera.imageHeight)
+	return sum / float64(len(camera.lights))
+}
 
-		for x := 0; x < camera.imageWidth; x++ {
-			c := NewColor(0, 0, 0) // Start with black
+// Returns the light that reaches the hit point, and is scattered along the incoming ray, from a direction of the
+// background picked proportionally to its brightness, unless some object is in the way
+func (camera PositionableCamera) sampleBackground(rng *Rng, background SampledBackground, mat PDFMaterial, world Hittable, ray Ray, rec *HitRecord) Color {
+	if camera.fogDensity > 0 {
+		return Color{0, 0, 0} // The background is infinitely far, behind an infinite amount of fog
+	}
 
-			// Accumulate all samples into one color, this may bring the color components out of their nominal [0,1] range
-			for sample := 0; sample < samplesPerPixel; sample++ {
-				ray := camera.getRay(x, y)
-				c = c.Add(camera.RayColor(ray, world, maxRayDepth))
-			}
+	direction, backgroundPDF := background.Sample(rng)
+
+	if backgroundPDF <= 0 {
+		return Color{0, 0, 0}
+	}
+
+	wo := ray.Direction().UnitVector().Negate()
+	f := mat.Eval(wo, direction, rec)
+
+	if f.NearZero() {
+		return Color{0, 0, 0}
+	}
+
+	shadowRay := NewRayWithTime(rec.P, direction, ray.Time())
+
+	if world.Hit(shadowRay, 0.001, math.Inf(+1), &HitRecord{}) {
+		return Color{0, 0, 0}
+	}
 
-			c = c.Div(float64(samplesPerPixel)) // Bring the color components back to the [0,1] range
+	weight := powerHeuristic(backgroundPDF, mat.PDF(wo, direction, rec))
+	return background.Value(shadowRay).MultiplyComponents(f).Mul(weight / backgroundPDF)
+}
 
-			// Apply gamma correction
-			c.X = LinearToGamma(c.X)
-			c.Y = LinearToGamma(c.Y)
-			c.Z = LinearToGamma(c.Z)
+// Weight of a sample taken with probability density pdf, when another strategy could have taken the same sample
+// with otherPDF
+func powerHeuristic(pdf, otherPDF float64) float64 {
+	return pdf * pdf / (pdf*pdf + otherPDF*otherPDF)
+}
 
-			ir := int(255.999 * c.X)
-			ig := int(255.999 * c.Y)
-			ib := int(255.999 * c.Z)
+// Renders a single scanline into the framebuffer
+func (camera PositionableCamera) renderScanline(rng *Rng, fb *Framebuffer, y int, world Hittable) {
+	for x := 0; x < camera.imageWidth; x++ {
+		c := NewColor(0, 0, 0) // Start with black
 
-			fmt.Fprintf(w, "%d %d %d\n", ir, ig, ib)
+		// Accumulate all samples into one color, this may bring the color components out of their nominal [0,1] range
+		for sample := 0; sample < camera.samplesPerPixel; sample++ {
+			ray := camera.getRay(rng, x, y)
+			c = c.Add(camera.RayColor(rng, ray, world, camera.maxRayDepth))
 		}
-		fmt.Fprintln(w)
+
+		c = c.Div(float64(camera.samplesPerPixel)) // Bring the color components back to the [0,1] range
+
+		fb.Set(x, y, c)
 	}
+}
+
+// Renders the image on a pool of workers, each one picking the next scanline to render until the image is complete
+func (camera *PositionableCamera) Render(world Hittable) *Framebuffer {
+	camera.Initialize()
+
+	workers := camera.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	// Every scanline is rendered with its own sequence of random numbers, derived from the global generator.
+	// This way the image only depends on the random seed, not on the number of workers or on how they are scheduled.
+	baseSeed := globalRng.Int63()
+
+	fb := NewFramebuffer(camera.imageWidth, camera.imageHeight)
+	next := make(chan int)
+	done := int32(0)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			rng := NewRng(0) // Each worker owns its generator
+
+			for y := range next {
+				rng.Seed(baseSeed + int64(y))
+				camera.renderScanline(rng, fb, y, world)
+
+				n := atomic.AddInt32(&done, 1)
+				fmt.Fprintf(os.Stderr, "Rendered scanline %d of %d (%d%%)\n", n, camera.imageHeight, int(n)*100/camera.imageHeight)
+			}
+		}()
+	}
+
+	for y := 0; y < camera.imageHeight; y++ {
+		next <- y
+	}
+	close(next)
+
+	wg.Wait()
 
-	fmt.Fprintln(w)
+	return fb
 }
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRenderDoesNotDependOnWorkers(t *testing.T) {
+	world := NewHittableList()
+	world.Add(NewSphereWithMaterial(NewPoint3(0, -100.5, -1), 100, NewLambertianMaterial(NewColor(0.8, 0.8, 0.0))))
+	world.Add(NewSphereWithMaterial(NewPoint3(0, 0, -1), 0.5, NewDielectricMaterial(1.5)))
+
+	render := func(workers int) *Framebuffer {
+		cam := NewPositionableCamera()
+		cam.SetImageWidth(32)
+		cam.SetWorkers(workers)
+		cam.SetSamplesPerPixel(4)
+
+		SetRandomSeed(42)
+		return cam.Render(world)
+	}
+
+	a := render(1)
+	b := render(5)
+
+	for y := 0; y < a.Height(); y++ {
+		for x := 0; x < a.Width(); x++ {
+			if a.At(x, y) != b.At(x, y) {
+				t.Fatalf("rendering with 1 and 5 workers produced different colors at %d, %d", x, y)
+			}
+		}
+	}
+}
+
+// Checks that a camera that samples the lights explicitly sees the same average brightness along the ray as one
+// that relies on scattered rays alone, with much less noise
+func checkVarianceReduction(t *testing.T, world Hittable, ray Ray, scattered, sampled PositionableCamera) {
+	t.Helper()
+
+	// Returns the mean and variance of the brightness seen by the ray
+	estimate := func(cam PositionableCamera) (mean, variance float64) {
+		const samples = 20000
+		rng := NewRng(1)
+		sum, sumSquares := 0.0, 0.0
+
+		for i := 0; i < samples; i++ {
+			l := Luminance(cam.RayColor(rng, ray, world, 2))
+			sum += l
+			sumSquares += l * l
+		}
+
+		mean = sum / samples
+		variance = sumSquares/samples - mean*mean
+		return mean, variance / samples // Variance of the mean
+	}
+
+	scatteredMean, scatteredVariance := estimate(scattered)
+	sampledMean, sampledVariance := estimate(sampled)
+
+	if diff := math.Abs(scatteredMean - sampledMean); diff > 4*math.Sqrt(scatteredVariance+sampledVariance) {
+		t.Errorf("average brightness %v with sampling, %v without", sampledMean, scatteredMean)
+	}
+
+	if sampledVariance > scatteredVariance/5 {
+		t.Errorf("variance %v with sampling, %v without", sampledVariance, scatteredVariance)
+	}
+}
+
+// Sampling the bright regions of an environment explicitly must give the same average brightness as relying on
+// scattered rays alone, with much less noise
+func TestEnvironmentSamplingReducesVariance(t *testing.T) {
+	// A dim sky with a small and very bright sun
+	image := NewFramebuffer(64, 32)
+	for y := 0; y < image.Height(); y++ {
+		for x := 0; x < image.Width(); x++ {
+			image.Set(x, y, NewColor(0.05, 0.05, 0.1))
+		}
+	}
+
+	for y := 4; y < 6; y++ {
+		for x := 20; x < 22; x++ {
+			image.Set(x, y, NewColor(1000, 900, 800))
+		}
+	}
+
+	environment := NewEnvironmentBackground(image)
+
+	world := NewHittableList()
+	world.Add(NewSphereWithMaterial(NewPoint3(0, -1000, 0), 1000, NewLambertianMaterial(NewColor(0.5, 0.5, 0.5))))
+
+	// Hiding the Sample() and PDF() methods leaves only the scattered rays
+	scattered := NewPositionableCamera()
+	scattered.SetBackground(struct{ Background }{environment})
+
+	sampled := NewPositionableCamera()
+	sampled.SetBackground(environment)
+
+	checkVarianceReduction(t, world, NewRay(NewPoint3(0, 1, 0), NewVec3(0, -1, 0)), scattered, sampled)
+}
+
+// Sampling a small light explicitly must give the same average brightness as waiting for scattered rays to hit it,
+// with much less noise
+func TestLightSamplingReducesVariance(t *testing.T) {
+	world := NewHittableList()
+	world.Add(NewQuad(NewPoint3(-10, 0, -10), NewVec3(0, 0, 20), NewVec3(20, 0, 0), NewLambertianMaterial(NewColor(0.5, 0.5, 0.5))))
+
+	light := NewQuad(NewPoint3(-0.1, 2, -0.1), NewVec3(0.2, 0, 0), NewVec3(0, 0, 0.2), NewDiffuseLight(NewColor(100, 100, 100)))
+	world.Add(light)
+
+	scattered := NewPositionableCamera()
+	scattered.SetBackground(NewSolidBackground(NewColor(0, 0, 0)))
+
+	sampled := scattered
+	sampled.SetLights(light)
+
+	checkVarianceReduction(t, world, NewRay(NewPoint3(1, 1, 0), NewVec3(-1, -1, 0)), scattered, sampled)
+}
+
+func TestShutter(t *testing.T) {
+	cam := NewPositionableCamera()
+	cam.SetImageWidth(8)
+	cam.Initialize()
+
+	rng := NewRng(1)
+
+	if ray := cam.getRay(rng, 0, 0); ray.Time() != 0 {
+		t.Errorf("with the default shutter the ray is cast at time %v", ray.Time())
+	}
+
+	cam.SetShutter(1, 1.5)
+	min, max := math.Inf(+1), math.Inf(-1)
+
+	for i := 0; i < 1000; i++ {
+		time := cam.getRay(rng, 0, 0).Time()
+		min, max = math.Min(min, time), math.Max(max, time)
+	}
+
+	if min < 1 || max >= 1.5 || max-min < 0.45 {
+		t.Errorf("ray times go from %v to %v, want them spread over [1, 1.5)", min, max)
+	}
+}
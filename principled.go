@@ -0,0 +1,367 @@
+package main
+
+import "math"
+
+// A single material with intuitive parameters, modeled after the "principled" material of Disney (Burley 2012).
+// It layers a diffuse base, a specular reflection, a transparent coating (clearcoat) and refraction into glass, and
+// every parameter can change across the surface with a texture. Parameters other than the base color are in [0, 1]
+// and are read from the red channel of their texture.
+//
+// At the extreme parameter values it converges to the simpler materials: with no metallic, transmission, specular,
+// sheen and clearcoat it's a LambertianMaterial, a smooth metal is a MetalMaterial without fuzz, and smooth white
+// glass is a DielectricMaterial. Metals reflect their base color at every angle, like MetalMaterial, and a smooth
+// surface reflects and refracts like a mirror, so the look doesn't change abruptly next to these values.
+type PrincipledMaterial struct {
+	baseColor      Texture
+	metallic       Texture // Blends from a dielectric (e.g. plastic) to a metal, whose reflections take the base color
+	roughness      Texture // Spreads the specular reflections and refractions
+	specular       Texture // Strength of the specular reflection of dielectrics, 0.5 is about 4%, like most materials
+	specularTint   Texture // Tints the specular reflection of dielectrics towards the base color
+	sheen          Texture // A soft reflection at grazing angles, like on cloth
+	clearcoat      Texture // Strength of a colorless specular coating, like varnish
+	clearcoatGloss Texture // Smoothness of the coating
+	transmission   Texture // Blends from an opaque dielectric to glass, whose refractions take the base color
+	ior            float64 // Index of refraction of the glass
+}
+
+// Principled material with the default parameters: a slightly shiny plastic of the given color
+func NewPrincipledMaterial(baseColor Texture) PrincipledMaterial {
+	return PrincipledMaterial{
+		baseColor:      baseColor,
+		metallic:       gray(0),
+		roughness:      gray(0.5),
+		specular:       gray(0.5),
+		specularTint:   gray(0),
+		sheen:          gray(0),
+		clearcoat:      gray(0),
+		clearcoatGloss: gray(1),
+		transmission:   gray(0),
+		ior:            1.5,
+	}
+}
+
+func (m *PrincipledMaterial) SetClearcoat(t Texture) {
+	m.clearcoat = t
+}
+
+func (m *PrincipledMaterial) SetClearcoatGloss(t Texture) {
+	m.clearcoatGloss = t
+}
+
+func (m *PrincipledMaterial) SetIOR(ior float64) {
+	m.ior = ior
+}
+
+func (m *PrincipledMaterial) SetMetallic(t Texture) {
+	m.metallic = t
+}
+
+func (m *PrincipledMaterial) SetRoughness(t Texture) {
+	m.roughness = t
+}
+
+func (m *PrincipledMaterial) SetSheen(t Texture) {
+	m.sheen = t
+}
+
+func (m *PrincipledMaterial) SetSpecular(t Texture) {
+	m.specular = t
+}
+
+func (m *PrincipledMaterial) SetSpecularTint(t Texture) {
+	m.specularTint = t
+}
+
+func (m *PrincipledMaterial) SetTransmission(t Texture) {
+	m.transmission = t
+}
+
+// The parameters of the material at a hit point, turned into the lobes that make up the scattering function
+type principledLobes struct {
+	base          Color
+	diffuse       float64 // Weight of the diffuse lobe
+	sheen         float64
+	specular      float64 // Weight of the specular lobe
+	metallic      float64
+	dielectric    Color   // Reflectance of dielectrics at normal incidence
+	dielectric90  float64 // Reflectance of dielectrics at grazing angles
+	clearcoat     float64 // Weight of the clearcoat lobe
+	transmission  float64 // Weight of the glass lobe
+	distribution  ggxDistribution
+	coat          ggxDistribution
+	glass         RoughDielectricMaterial
+	eta           float64
+	probabilities [4]float64 // Probability of sampling the diffuse, specular, clearcoat and glass lobes
+}
+
+func (m PrincipledMaterial) scalar(t Texture, rec *HitRecord) float64 {
+	return math.Min(math.Max(t.Value(rec.U, rec.V, rec.P).X, 0), 1)
+}
+
+// Returns the simpler material that this one converges to at the hit point, if the parameters are at their extreme
+// values: it's cheaper to evaluate
+func (m PrincipledMaterial) reduce(rec *HitRecord) (PDFMaterial, bool) {
+	metallic := m.scalar(m.metallic, rec)
+	transmission := m.scalar(m.transmission, rec)
+	clearcoat := m.scalar(m.clearcoat, rec)
+
+	if clearcoat > 0 {
+		return nil, false
+	}
+
+	base := m.baseColor.Value(rec.U, rec.V, rec.P)
+	roughness := m.scalar(m.roughness, rec)
+
+	switch {
+	case metallic == 1 && roughness == 0:
+		return NewMetalMaterial(base, 0), true
+
+	case metallic == 0 && transmission == 1 && roughness == 0 && base == (Color{1, 1, 1}):
+		return NewDielectricMaterial(m.ior), true
+
+	case metallic == 0 && transmission == 0 && m.scalar(m.specular, rec) == 0 && m.scalar(m.sheen, rec) == 0:
+		return NewLambertianMaterial(base), true
+	}
+
+	return nil, false
+}
+
+func (m PrincipledMaterial) lobes(rec *HitRecord) principledLobes {
+	metallic := m.scalar(m.metallic, rec)
+	transmission := m.scalar(m.transmission, rec)
+	roughness := m.scalar(m.roughness, rec)
+
+	l := principledLobes{base: m.baseColor.Value(rec.U, rec.V, rec.P), metallic: metallic}
+	l.diffuse = (1 - metallic) * (1 - transmission)
+	l.sheen = m.scalar(m.sheen, rec)
+	l.clearcoat = m.scalar(m.clearcoat, rec) / 4
+	l.transmission = (1 - metallic) * transmission
+
+	// Dielectrics reflect up to 8% of the light at normal incidence, possibly tinted by the hue of the base color,
+	// metals reflect their base color. Glass already reflects light by itself.
+	tint := Color{1, 1, 1}
+	if luminance := Luminance(l.base); luminance > 0 {
+		tint = l.base.Div(luminance)
+	}
+
+	l.dielectric = Color{1, 1, 1}.Mul(1 - m.scalar(m.specularTint, rec)).Add(tint.Mul(m.scalar(m.specularTint, rec)))
+	l.dielectric = l.dielectric.Mul(0.08 * m.scalar(m.specular, rec))
+	l.specular = 1 - l.transmission
+
+	// Without any reflectance at normal incidence there is none at grazing angles either, so that no specular means
+	// no reflections at all
+	l.dielectric90 = math.Min(1, 50*Luminance(l.dielectric))
+
+	l.distribution = newGGXDistribution(roughness, roughness)
+	coatAlpha := lerp(m.scalar(m.clearcoatGloss, rec), 0.1, 0.001)
+	l.coat = newGGXDistributionFromAlpha(coatAlpha, coatAlpha)
+	l.glass = RoughDielectricMaterial{ir: m.ior, distribution: l.distribution}
+	l.eta = l.glass.eta(rec)
+
+	// Lobes are sampled roughly in proportion to how much light they scatter
+	l.probabilities = [4]float64{
+		l.diffuse * (Luminance(l.base) + l.sheen),
+		l.specular * math.Sqrt(Luminance(l.dielectric.Mul(1-metallic).Add(l.base.Mul(metallic)))),
+		l.clearcoat,
+		l.transmission,
+	}
+
+	sum := 0.0
+	for _, p := range l.probabilities {
+		sum += p
+	}
+
+	for i := range l.probabilities {
+		if sum > 0 {
+			l.probabilities[i] /= sum
+		}
+	}
+
+	return l
+}
+
+// Schlick's approximation of the Fresnel reflectance, from f0 at normal incidence to f90 at grazing angles
+func schlickFresnel(f0 Color, f90, cosTheta float64) Color {
+	w := math.Pow(1-math.Min(math.Max(cosTheta, 0), 1), 5)
+	return f0.Mul(1 - w).Add(Color{f90, f90, f90}.Mul(w))
+}
+
+// Reflectance of the specular lobe on a microfacet seen at an angle whose cosine is cosTheta
+func (l principledLobes) specularReflectance(cosTheta float64) Color {
+	return schlickFresnel(l.dielectric, l.dielectric90, cosTheta).Mul(1 - l.metallic).Add(l.base.Mul(l.metallic))
+}
+
+// Returns the scattering function times cos(wi), in the local shading frame. Smooth lobes scatter in a single
+// direction, so they are left out, see sample().
+func (l principledLobes) eval(wo, wi Vec3) Color {
+	f := Color{0, 0, 0}
+
+	if wo.Z <= 0 {
+		return f
+	}
+
+	if wi.Z > 0 {
+		h := wo.Add(wi).UnitVector()
+		sheen := l.sheen * math.Pow(1-math.Min(math.Max(wi.Dot(h), 0), 1), 5)
+		f = f.Add(l.base.Div(math.Pi).Add(Color{sheen, sheen, sheen}).Mul(l.diffuse * wi.Z))
+
+		if r, h := l.distribution.reflection(wo, wi); r > 0 && !l.distribution.smooth() {
+			f = f.Add(l.specularReflectance(wo.Dot(h)).Mul(l.specular * r))
+		}
+
+		if r, h := l.coat.reflection(wo, wi); r > 0 {
+			f = f.Add(schlickFresnel(Color{0.04, 0.04, 0.04}, 1, wo.Dot(h)).Mul(l.clearcoat * r))
+		}
+	}
+
+	if l.transmission > 0 && !l.distribution.smooth() {
+		// Only the light that goes through the glass takes its color
+		glass, reflection := l.glass.evalLocal(wo, wi, l.eta)
+		tint := l.base
+		if reflection {
+			tint = Color{1, 1, 1}
+		}
+		f = f.Add(tint.Mul(l.transmission * glass))
+	}
+
+	return f
+}
+
+// Returns the probability density of sample() picking wi, in the local shading frame
+func (l principledLobes) pdf(wo, wi Vec3) float64 {
+	if wo.Z <= 0 {
+		return 0
+	}
+
+	pdf := l.probabilities[0] * math.Max(0, wi.Z) / math.Pi
+	pdf += l.probabilities[2] * l.coat.reflectionPDF(wo, wi)
+
+	if !l.distribution.smooth() {
+		pdf += l.probabilities[1] * l.distribution.reflectionPDF(wo, wi)
+
+		if l.probabilities[3] > 0 {
+			pdf += l.probabilities[3] * l.glass.localPDF(wo, wi, l.eta)
+		}
+	}
+
+	return pdf
+}
+
+// Picks one of the lobes, then a direction from it, in the local shading frame. The smooth specular and glass lobes
+// scatter in a single direction: the sample is specular and already has its attenuation, otherwise only the
+// direction is set.
+func (l principledLobes) sample(rng *Rng, wo Vec3) (ScatterSample, bool) {
+	xi := rng.Double()
+
+	switch {
+	case xi < l.probabilities[0]:
+		wi := NewVec3(0, 0, 1).Add(rng.UnitVec3()) // Cosine distribution, like LambertianMaterial
+		if wi.NearZero() {
+			return ScatterSample{Direction: NewVec3(0, 0, 1)}, true
+		}
+		return ScatterSample{Direction: wi.UnitVector()}, true
+
+	case xi < l.probabilities[0]+l.probabilities[1]:
+		if l.distribution.smooth() {
+			attenuation := l.specularReflectance(wo.Z).Mul(l.specular / l.probabilities[1])
+			return ScatterSample{Direction: NewVec3(-wo.X, -wo.Y, wo.Z), Attenuation: attenuation, Specular: true}, true
+		}
+
+		wi, ok := l.distribution.sampleReflection(rng, wo)
+		return ScatterSample{Direction: wi}, ok
+
+	case xi < l.probabilities[0]+l.probabilities[1]+l.probabilities[2]:
+		wi, ok := l.coat.sampleReflection(rng, wo)
+		return ScatterSample{Direction: wi}, ok
+	}
+
+	if l.distribution.smooth() {
+		// Reflection and refraction are picked in proportion to the Fresnel reflectance, which cancels out
+		sample := ScatterSample{Direction: NewVec3(-wo.X, -wo.Y, wo.Z), Attenuation: Color{1, 1, 1}, Specular: true}
+
+		if rng.Double() >= FresnelDielectric(wo.Z, l.eta) {
+			sample.Direction = Refract(wo.Negate(), NewVec3(0, 0, 1), 1/l.eta).UnitVector()
+			sample.Attenuation = l.base
+		}
+
+		sample.Attenuation = sample.Attenuation.Mul(l.transmission / l.probabilities[3])
+		return sample, true
+	}
+
+	wi, ok := l.glass.sampleLocal(rng, wo, l.eta)
+	return ScatterSample{Direction: wi}, ok
+}
+
+func (m PrincipledMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	if reduced, ok := m.reduce(rec); ok {
+		return reduced.Scatter(rng, ray, rec, attenuation, scattered)
+	}
+
+	sample, ok := m.Sample(rng, ray, rec)
+
+	if !ok {
+		return false
+	}
+
+	*scattered = NewRayWithTime(rec.P, sample.Direction, ray.Time())
+	*attenuation = sample.Attenuation
+
+	return true
+}
+
+// The direction is picked from one of the lobes, but it could have been picked by any of them: the attenuation is
+// the whole scattering function divided by the combined density of all the lobes
+func (m PrincipledMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	if reduced, ok := m.reduce(rec); ok {
+		return reduced.Sample(rng, ray, rec)
+	}
+
+	wo := ray.Direction().UnitVector().Negate()
+
+	l := m.lobes(rec)
+	localWo, ok := outgoingInShadingFrame(rec, wo)
+
+	if !ok {
+		return ScatterSample{}, false
+	}
+
+	sample, ok := l.sample(rng, localWo)
+
+	if !ok {
+		return ScatterSample{}, false
+	}
+
+	localWi := sample.Direction
+	sample.Direction = fromShadingFrame(rec, localWi)
+
+	if sample.Specular {
+		return sample, true
+	}
+
+	pdf := l.pdf(localWo, localWi)
+
+	if pdf <= 0 {
+		return ScatterSample{}, false
+	}
+
+	sample.PDF = pdf
+	sample.Attenuation = l.eval(localWo, localWi).Div(pdf)
+
+	return sample, true
+}
+
+func (m PrincipledMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	if reduced, ok := m.reduce(rec); ok {
+		return reduced.Eval(wo, wi, rec)
+	}
+
+	return m.lobes(rec).eval(toShadingFrame(rec, wo), toShadingFrame(rec, wi))
+}
+
+func (m PrincipledMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	if reduced, ok := m.reduce(rec); ok {
+		return reduced.PDF(wo, wi, rec)
+	}
+
+	return m.lobes(rec).pdf(toShadingFrame(rec, wo), toShadingFrame(rec, wi))
+}
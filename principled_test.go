@@ -0,0 +1,115 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+// At the extreme parameter values the principled material must scatter rays exactly like the simpler materials
+func TestPrincipledReducesToSimpleMaterials(t *testing.T) {
+	base := NewColor(0.8, 0.4, 0.2)
+
+	lambertian := NewPrincipledMaterial(NewSolidColor(base))
+	lambertian.SetSpecular(gray(0))
+
+	metal := NewPrincipledMaterial(NewSolidColor(base))
+	metal.SetMetallic(gray(1))
+	metal.SetRoughness(gray(0))
+
+	glass := NewPrincipledMaterial(gray(1))
+	glass.SetTransmission(gray(1))
+	glass.SetRoughness(gray(0))
+	glass.SetIOR(1.5)
+
+	tests := []struct {
+		principled PrincipledMaterial
+		simple     Material
+	}{
+		{lambertian, NewLambertianMaterial(base)},
+		{metal, NewMetalMaterial(base, 0)},
+		{glass, NewDielectricMaterial(1.5)},
+	}
+
+	ray := NewRay(NewPoint3(0, 1, 1), NewVec3(0.3, -1, -1))
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 1, 0), T: 1, FrontFace: true}
+	rec.SetTangentFrame(NewVec3(1, 0, 0))
+
+	for _, test := range tests {
+		rng1, rng2 := NewRng(1), NewRng(1)
+
+		for i := 0; i < 100; i++ {
+			scattered1, attenuation1 := Ray{}, Color{}
+			scattered2, attenuation2 := Ray{}, Color{}
+
+			ok1 := test.principled.Scatter(rng1, ray, &rec, &attenuation1, &scattered1)
+			ok2 := test.simple.Scatter(rng2, ray, &rec, &attenuation2, &scattered2)
+
+			if ok1 != ok2 || attenuation1 != attenuation2 || scattered1 != scattered2 {
+				t.Fatalf("%T: principled material scattered %v %v, want %v %v", test.simple, scattered1, attenuation1, scattered2, attenuation2)
+			}
+		}
+	}
+}
+
+// A white surface may not scatter more light than it receives
+func TestPrincipledSampling(t *testing.T) {
+	m := NewPrincipledMaterial(gray(1))
+	m.SetMetallic(gray(0.3))
+	m.SetRoughness(gray(0.4))
+	m.SetSpecularTint(gray(0.5))
+	m.SetSheen(gray(0.5))
+	m.SetClearcoat(gray(0.5))
+	m.SetClearcoatGloss(gray(0.5))
+	m.SetTransmission(gray(0.4))
+
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), FrontFace: true}
+	rec.SetTangentFrame(NewVec3(1, 0, 0))
+	wo := NewVec3(0.5, 0.2, 0.8).UnitVector()
+
+	if albedo := checkSampling(t, m, &rec, wo).albedo; albedo.X > 1.02 || albedo.Y > 1.02 || albedo.Z > 1.02 {
+		t.Errorf("a white surface scatters %v of the light it receives", albedo)
+	}
+}
+
+// Next to the extreme parameter values the general model must scatter about as much light as the simpler materials,
+// even at grazing angles, so that textured parameters don't flip between different looks
+func TestPrincipledConvergesToSimpleMaterials(t *testing.T) {
+	base := NewColor(0.8, 0.4, 0.2)
+
+	tests := []struct {
+		name                string
+		metallic, roughness float64
+	}{
+		{"smooth", 1, 0.001},
+		{"almost smooth", 1, 0.04},
+		{"almost metallic", 0.999, 0},
+	}
+
+	rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), FrontFace: true}
+	rec.SetTangentFrame(NewVec3(1, 0, 0))
+	wo := NewVec3(math.Sqrt(1-0.2*0.2), 0, 0.2)
+	ray := NewRay(rec.P.Add(wo), wo.Negate())
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			m := NewPrincipledMaterial(NewSolidColor(base))
+			m.SetMetallic(gray(test.metallic))
+			m.SetRoughness(gray(test.roughness))
+
+			// The lobes are too narrow for checkSampling() to integrate their density
+			rng := NewRng(1)
+			const samples = 10000
+			albedo := Color{}
+
+			for i := 0; i < samples; i++ {
+				if sample, ok := m.Sample(rng, ray, &rec); ok {
+					albedo = albedo.Add(sample.Attenuation.Div(samples))
+				}
+			}
+
+			if albedo.Sub(base).Length() > 0.02 {
+				t.Errorf("scatters %v of the light it receives, a smooth metal scatters %v", albedo, base)
+			}
+		})
+	}
+}
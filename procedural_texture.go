@@ -0,0 +1,147 @@
+package main
+
+import (
+	"math"
+	"sort"
+)
+
+// A color ramp maps numbers to colors, blending linearly between colors placed at given positions.
+// Procedural textures use it to turn noise values into colors.
+type ColorRamp struct {
+	stops []ColorStop
+}
+
+type ColorStop struct {
+	Position float64
+	Color    Color
+}
+
+// Stops can be given in any order. A ramp without stops is black.
+func NewColorRamp(stops ...ColorStop) ColorRamp {
+	sorted := append([]ColorStop(nil), stops...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Position < sorted[j].Position })
+
+	return ColorRamp{stops: sorted}
+}
+
+// A ramp that goes from the first color at 0 to the last one at 1, with the others evenly spaced in between
+func NewEvenColorRamp(colors ...Color) ColorRamp {
+	stops := make([]ColorStop, len(colors))
+
+	for i, c := range colors {
+		stops[i] = ColorStop{Color: c}
+
+		if len(colors) > 1 {
+			stops[i].Position = float64(i) / float64(len(colors)-1)
+		}
+	}
+
+	return NewColorRamp(stops...)
+}
+
+// Returns the color at t, values before the first stop or after the last one take the color of that stop
+func (r ColorRamp) At(t float64) Color {
+	if len(r.stops) == 0 {
+		return Color{0, 0, 0}
+	}
+
+	// Index of the first stop after t
+	i := sort.Search(len(r.stops), func(i int) bool { return r.stops[i].Position > t })
+
+	if i == 0 {
+		return r.stops[0].Color
+	}
+
+	if i == len(r.stops) {
+		return r.stops[i-1].Color
+	}
+
+	a, b := r.stops[i-1], r.stops[i]
+	alpha := (t - a.Position) / (b.Position - a.Position)
+
+	return a.Color.Mul(1 - alpha).Add(b.Color.Mul(alpha))
+}
+
+// The procedural textures below are all functions of the position in space, scaled by the given factor: the higher
+// the scale, the smaller the details.
+
+// Plain Perlin noise, the ramp goes from the lowest noise values at 0 to the highest at 1
+type NoiseTexture struct {
+	noise *Perlin
+	scale float64
+	ramp  ColorRamp
+}
+
+func NewNoiseTexture(noise *Perlin, scale float64, ramp ColorRamp) NoiseTexture {
+	return NoiseTexture{noise: noise, scale: scale, ramp: ramp}
+}
+
+func (t NoiseTexture) Value(u, v float64, p Point3) Color {
+	return t.ramp.At(0.5 * (1 + t.noise.Noise(p.Mul(t.scale))))
+}
+
+// Turbulence made of several octaves of noise, which gives a cloudy look
+type TurbulenceTexture struct {
+	noise   *Perlin
+	scale   float64
+	octaves int
+	ramp    ColorRamp
+}
+
+func NewTurbulenceTexture(noise *Perlin, scale float64, octaves int, ramp ColorRamp) TurbulenceTexture {
+	return TurbulenceTexture{noise: noise, scale: scale, octaves: octaves, ramp: ramp}
+}
+
+func (t TurbulenceTexture) Value(u, v float64, p Point3) Color {
+	return t.ramp.At(t.noise.Turbulence(p.Mul(t.scale), t.octaves))
+}
+
+// Marble veins are layers that repeat along the Z axis, each parallel to the XY plane, made wavy by turbulence
+type MarbleTexture struct {
+	noise *Perlin
+	scale float64
+	ramp  ColorRamp
+}
+
+func NewMarbleTexture(noise *Perlin, scale float64, ramp ColorRamp) MarbleTexture {
+	return MarbleTexture{noise: noise, scale: scale, ramp: ramp}
+}
+
+func (t MarbleTexture) Value(u, v float64, p Point3) Color {
+	q := p.Mul(t.scale)
+	return t.ramp.At(0.5 * (1 + math.Sin(q.Z+10*t.noise.Turbulence(q, 7))))
+}
+
+// Wood grain is made of concentric rings around the Y axis, distorted by turbulence.
+// The ramp goes from 0 at the inner edge of a ring to 1 at its outer edge.
+type WoodTexture struct {
+	noise *Perlin
+	scale float64
+	ramp  ColorRamp
+}
+
+func NewWoodTexture(noise *Perlin, scale float64, ramp ColorRamp) WoodTexture {
+	return WoodTexture{noise: noise, scale: scale, ramp: ramp}
+}
+
+func (t WoodTexture) Value(u, v float64, p Point3) Color {
+	q := p.Mul(t.scale)
+	rings := math.Hypot(q.X, q.Z) + 0.5*t.noise.Turbulence(q, 4)
+
+	return t.ramp.At(rings - math.Floor(rings))
+}
+
+// Cells around random points, the ramp goes from 0 at the center of a cell to 1 towards its borders
+type WorleyTexture struct {
+	noise *Worley
+	scale float64
+	ramp  ColorRamp
+}
+
+func NewWorleyTexture(noise *Worley, scale float64, ramp ColorRamp) WorleyTexture {
+	return WorleyTexture{noise: noise, scale: scale, ramp: ramp}
+}
+
+func (t WorleyTexture) Value(u, v float64, p Point3) Color {
+	return t.ramp.At(t.noise.Noise(p.Mul(t.scale)))
+}
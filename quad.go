@@ -0,0 +1,94 @@
+package main
+
+import "math"
+
+// A quad is a parallelogram: the corner q and the two edges u and v starting from it define the other three corners
+// q+u, q+v and q+u+v. The front face is the one seen from the side the normal u×v points to, i.e. where the corners
+// q, q+u, q+u+v, q+v go counterclockwise.
+type Quad struct {
+	q      Point3
+	u, v   Vec3
+	w      Vec3 // Used to find the coordinates of a point of the plane along u and v
+	normal Vec3
+	d      float64 // The plane of the quad has equation normal·p = d
+	area   float64
+	mat    Material
+}
+
+func NewQuad(q Point3, u, v Vec3, mat Material) Quad {
+	n := u.Cross(v)
+	normal := n.UnitVector()
+
+	return Quad{q: q, u: u, v: v, w: n.Div(n.Dot(n)), normal: normal, d: normal.Dot(q), area: n.Length(), mat: mat}
+}
+
+// Returns the t at which the ray hits the plane normal·p = d, if it's in (rayTmin, rayTmax)
+func hitPlane(ray Ray, normal Vec3, d, rayTmin, rayTmax float64) (float64, bool) {
+	denom := normal.Dot(ray.Direction())
+
+	if math.Abs(denom) < 1e-8 {
+		return 0, false // The ray is parallel to the plane
+	}
+
+	t := (d - normal.Dot(ray.Origin())) / denom
+
+	return t, rayTmin < t && t < rayTmax
+}
+
+// Implement the Hittable interface
+func (quad Quad) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	t, ok := hitPlane(ray, quad.normal, quad.d, rayTmin, rayTmax)
+
+	if !ok {
+		return false
+	}
+
+	// The hit point is q + alpha*u + beta*v, it's inside the quad if both alpha and beta are in [0,1]
+	p := ray.At(t)
+	planar := p.Sub(quad.q)
+	alpha := quad.w.Dot(planar.Cross(quad.v))
+	beta := quad.w.Dot(quad.u.Cross(planar))
+
+	if alpha < 0 || alpha > 1 || beta < 0 || beta > 1 {
+		return false
+	}
+
+	rec.T = t
+	rec.P = p
+	rec.U = alpha
+	rec.V = beta
+	rec.Mat = quad.mat
+	rec.SetFaceNormal(ray, quad.normal)
+	rec.SetTangentFrame(quad.u)
+
+	return true
+}
+
+func (quad Quad) BoundingBox() AABB {
+	box := NewSurroundingAABB(
+		NewAABBFromPoints(quad.q, quad.q.Add(quad.u).Add(quad.v)),
+		NewAABBFromPoints(quad.q.Add(quad.u), quad.q.Add(quad.v)))
+
+	return box.Pad(0.0001) // Quads lying on an axis plane would have a box with zero thickness
+}
+
+// Returns the box that has a and b at opposite corners, made of six quads facing outwards
+func NewBox(a, b Point3, mat Material) HittableList {
+	sides := NewHittableList()
+
+	min := NewPoint3(math.Min(a.X, b.X), math.Min(a.Y, b.Y), math.Min(a.Z, b.Z))
+	max := NewPoint3(math.Max(a.X, b.X), math.Max(a.Y, b.Y), math.Max(a.Z, b.Z))
+
+	dx := NewVec3(max.X-min.X, 0, 0)
+	dy := NewVec3(0, max.Y-min.Y, 0)
+	dz := NewVec3(0, 0, max.Z-min.Z)
+
+	sides.Add(NewQuad(NewPoint3(min.X, min.Y, max.Z), dx, dy, mat))          // Front
+	sides.Add(NewQuad(NewPoint3(max.X, min.Y, max.Z), dz.Negate(), dy, mat)) // Right
+	sides.Add(NewQuad(NewPoint3(max.X, min.Y, min.Z), dx.Negate(), dy, mat)) // Back
+	sides.Add(NewQuad(NewPoint3(min.X, min.Y, min.Z), dz, dy, mat))          // Left
+	sides.Add(NewQuad(NewPoint3(min.X, max.Y, max.Z), dx, dz.Negate(), mat)) // Top
+	sides.Add(NewQuad(NewPoint3(min.X, min.Y, min.Z), dx, dz, mat))          // Bottom
+
+	return sides
+}
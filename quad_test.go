@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestQuadHit(t *testing.T) {
+	quad := NewQuad(NewPoint3(-1, -1, -2), NewVec3(2, 0, 0), NewVec3(0, 4, 0), nil)
+	origin := NewPoint3(0, 0, 0)
+
+	rec := HitRecord{}
+	if !quad.Hit(NewRay(origin, NewVec3(0.5, 2, -2)), 0, math.Inf(+1), &rec) {
+		t.Fatalf("ray should hit the quad")
+	}
+
+	if rec.T != 1 || rec.U != 0.75 || rec.V != 0.75 {
+		t.Errorf("got t=%v u=%v v=%v, want t=1 u=0.75 v=0.75", rec.T, rec.U, rec.V)
+	}
+
+	// The normal u×v points towards the ray origin
+	if !rec.FrontFace || rec.Normal != NewVec3(0, 0, 1) || rec.Tangent != NewVec3(1, 0, 0) {
+		t.Errorf("got front face %v, normal %v and tangent %v", rec.FrontFace, rec.Normal, rec.Tangent)
+	}
+
+	// Seen from behind
+	if !quad.Hit(NewRay(NewPoint3(0, 0, -4), NewVec3(0, 0, 1)), 0, math.Inf(+1), &rec) || rec.FrontFace || rec.Normal != NewVec3(0, 0, -1) {
+		t.Errorf("got front face %v and normal %v from behind", rec.FrontFace, rec.Normal)
+	}
+
+	if quad.Hit(NewRay(origin, NewVec3(1.5, 0, -2)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray outside the quad should miss it")
+	}
+
+	if quad.Hit(NewRay(origin, NewVec3(1, 0, 0)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray parallel to the quad should miss it")
+	}
+
+	box := quad.BoundingBox()
+	if box.X != NewInterval(-1, 1) || box.Y != NewInterval(-1, 3) || box.Z.Size() <= 0 {
+		t.Errorf("bad bounding box %v", box)
+	}
+}
+
+func TestBox(t *testing.T) {
+	box := NewBox(NewPoint3(1, 1, 1), NewPoint3(-1, -2, -3), nil)
+
+	if b := box.BoundingBox(); b.X.Min > -1 || b.X.Max < 1 || b.Y.Min > -2 || b.Y.Max < 1 || b.Z.Min > -3 || b.Z.Max < 1 {
+		t.Errorf("bad bounding box %v", b)
+	}
+
+	// Rays from the center hit every side from the inside, where the outward normal must point away from the center
+	center := NewPoint3(0, -0.5, -1)
+	directions := []Vec3{NewVec3(1, 0, 0), NewVec3(-1, 0, 0), NewVec3(0, 1, 0), NewVec3(0, -1, 0), NewVec3(0, 0, 1), NewVec3(0, 0, -1)}
+
+	for _, d := range directions {
+		rec := HitRecord{}
+		if !box.Hit(NewRay(center, d), 0, math.Inf(+1), &rec) {
+			t.Fatalf("ray towards %v should hit the box", d)
+		}
+
+		if rec.FrontFace || rec.Normal != d.Negate() {
+			t.Errorf("towards %v got front face %v and normal %v", d, rec.FrontFace, rec.Normal)
+		}
+	}
+}
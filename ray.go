@@ -6,12 +6,17 @@ package main
 type Ray struct {
 	orig Point3
 	dir  Vec3
+	time float64 // The instant the ray is cast at, used by moving objects to render motion blur
 }
 
 func NewRay(origin Point3, direction Vec3) Ray {
 	return Ray{orig: origin, dir: direction}
 }
 
+func NewRayWithTime(origin Point3, direction Vec3, time float64) Ray {
+	return Ray{orig: origin, dir: direction, time: time}
+}
+
 func (r Ray) At(t float64) Point3 {
 	return r.orig.Add(r.dir.Mul(t))
 }
@@ -23,3 +28,7 @@ func (r Ray) Origin() Point3 {
 func (r Ray) Direction() Vec3 {
 	return r.dir
 }
+
+func (r Ray) Time() float64 {
+	return r.time
+}
@@ -0,0 +1,202 @@
+package main
+
+import "math"
+
+// Glass whose surface is made of microfacets, see ggxDistribution. Light is both reflected and refracted by the
+// microfacets, so the surface looks frosted, like sandblasted glass or a plastic diffuser.
+type RoughDielectricMaterial struct {
+	ir           float64
+	distribution ggxDistribution
+}
+
+// Rough dielectric material with roughness in [0, 1] along the tangent (u) and the bitangent (v) of the surface.
+// A roughness of 0 gives smooth glass.
+func NewRoughDielectricMaterial(indexOfRefraction, roughnessU, roughnessV float64) RoughDielectricMaterial {
+	return RoughDielectricMaterial{ir: indexOfRefraction, distribution: newGGXDistribution(roughnessU, roughnessV)}
+}
+
+// Fraction of the light reflected by the boundary between two dielectrics, for unpolarized light hitting it at an
+// angle whose cosine is cosTheta. Eta is the ratio between the index of refraction of the far side and the one of the
+// near side. Unlike SchlickReflectance() it is exact, and returns 1 on total internal reflection.
+func FresnelDielectric(cosTheta, eta float64) float64 {
+	cosTheta = math.Min(math.Max(cosTheta, 0), 1)
+
+	sin2T := (1 - cosTheta*cosTheta) / (eta * eta)
+	if sin2T >= 1 {
+		return 1
+	}
+
+	cosT := math.Sqrt(1 - sin2T)
+	rParallel := (eta*cosTheta - cosT) / (eta*cosTheta + cosT)
+	rPerpendicular := (cosTheta - eta*cosT) / (cosTheta + eta*cosT)
+
+	return (rParallel*rParallel + rPerpendicular*rPerpendicular) / 2
+}
+
+// Ratio between the index of refraction of the far side of the surface and the one of the side the ray comes from
+func (m RoughDielectricMaterial) eta(rec *HitRecord) float64 {
+	if rec.FrontFace {
+		return m.ir
+	}
+
+	return 1 / m.ir
+}
+
+func (m RoughDielectricMaterial) Scatter(rng *Rng, ray Ray, rec *HitRecord, attenuation *Color, scattered *Ray) bool {
+	sample, ok := m.Sample(rng, ray, rec)
+
+	if !ok {
+		return false
+	}
+
+	*scattered = NewRayWithTime(rec.P, sample.Direction, ray.Time())
+	*attenuation = sample.Attenuation
+
+	return true
+}
+
+// Like DielectricMaterial, the radiance is not scaled by the squared ratio of the indices when it is refracted: the
+// factors cancel out when the light enters and leaves an object, which is what usually happens
+func (m RoughDielectricMaterial) Sample(rng *Rng, ray Ray, rec *HitRecord) (ScatterSample, bool) {
+	wo := ray.Direction().UnitVector().Negate()
+	localWo, ok := outgoingInShadingFrame(rec, wo)
+
+	if !ok {
+		return ScatterSample{}, false
+	}
+
+	eta := m.eta(rec)
+
+	if m.distribution.smooth() {
+		wi := NewVec3(-localWo.X, -localWo.Y, localWo.Z)
+
+		if rng.Double() >= FresnelDielectric(localWo.Z, eta) {
+			wi = Refract(localWo.Negate(), NewVec3(0, 0, 1), 1/eta)
+		}
+
+		return ScatterSample{Direction: fromShadingFrame(rec, wi).UnitVector(), Attenuation: Color{1, 1, 1}, Specular: true}, true
+	}
+
+	wi, ok := m.sampleLocal(rng, localWo, eta)
+
+	if !ok {
+		return ScatterSample{}, false
+	}
+
+	// Fresnel and the distribution of normals cancel out with the pdf, in both cases
+	weight := m.distribution.G2(localWo, wi) / m.distribution.G1(localWo)
+	sample := ScatterSample{Direction: fromShadingFrame(rec, wi), Attenuation: Color{weight, weight, weight}}
+	sample.PDF = m.localPDF(localWo, wi, eta)
+
+	return sample, true
+}
+
+// Picks a visible microfacet, then reflects or refracts wo on it in proportion to the Fresnel reflectance.
+// Returns false if the new direction ends up on the wrong side of the surface.
+func (m RoughDielectricMaterial) sampleLocal(rng *Rng, wo Vec3, eta float64) (Vec3, bool) {
+	h := m.distribution.sampleVisibleNormal(rng, wo)
+
+	if rng.Double() < FresnelDielectric(wo.Dot(h), eta) {
+		wi := Reflect(wo.Negate(), h)
+		return wi, wi.Z > 0 // Reflected into the surface by a steep microfacet
+	}
+
+	wi := Refract(wo.Negate(), h, 1/eta).UnitVector()
+	return wi, wi.Z < 0 // Refracted out of the surface by a steep microfacet
+}
+
+// Returns the microfacet normal that reflects or refracts wo into wi, and whether it's a reflection.
+// The normal is zero if there is no such microfacet facing wo.
+func (m RoughDielectricMaterial) halfVector(wo, wi Vec3, eta float64) (Vec3, bool) {
+	reflection := wi.Z > 0
+	h := wo.Add(wi)
+
+	if !reflection {
+		h = wo.Add(wi.Mul(eta)) // Generalized half vector, see Snell's law
+	}
+
+	if h.NearZero() {
+		return Vec3{}, reflection
+	}
+
+	h = h.UnitVector()
+	if h.Z < 0 {
+		h = h.Negate()
+	}
+
+	// Both directions must be on the correct side of the microfacet
+	if wo.Dot(h) <= 0 || (reflection && wi.Dot(h) <= 0) || (!reflection && wi.Dot(h) >= 0) {
+		return Vec3{}, reflection
+	}
+
+	return h, reflection
+}
+
+// The scattering function is the sum of a reflection term F * D * G2 / (4 * cos(wo) * cos(wi)) and a transmission
+// term (1-F) * D * G2 * |wi.h| * |wo.h| * eta² / (cos(wo) * cos(wi) * (wi.h * eta + wo.h)²), times cos(wi)
+func (m RoughDielectricMaterial) Eval(wo, wi Vec3, rec *HitRecord) Color {
+	if m.distribution.smooth() {
+		return Color{0, 0, 0}
+	}
+
+	f, _ := m.evalLocal(toShadingFrame(rec, wo), toShadingFrame(rec, wi), m.eta(rec))
+
+	return Color{f, f, f}
+}
+
+// Returns the scattering function times cos(wi), and whether wi is a reflection of wo
+func (m RoughDielectricMaterial) evalLocal(wo, wi Vec3, eta float64) (float64, bool) {
+	if wo.Z <= 0 || wi.Z == 0 {
+		return 0, wi.Z > 0
+	}
+
+	h, reflection := m.halfVector(wo, wi, eta)
+
+	if h.NearZero() {
+		return 0, reflection
+	}
+
+	cosH := wo.Dot(h)
+	reflectance := FresnelDielectric(cosH, eta)
+	dg := m.distribution.D(h) * m.distribution.G2(wo, wi)
+
+	if reflection {
+		return reflectance * dg / (4 * wo.Z), true
+	}
+
+	denominator := wi.Dot(h)*eta + cosH
+	return (1 - reflectance) * dg * math.Abs(wi.Dot(h)) * cosH * eta * eta / (wo.Z * denominator * denominator), false
+}
+
+func (m RoughDielectricMaterial) PDF(wo, wi Vec3, rec *HitRecord) float64 {
+	if m.distribution.smooth() {
+		return 0
+	}
+
+	return m.localPDF(toShadingFrame(rec, wo), toShadingFrame(rec, wi), m.eta(rec))
+}
+
+// The density of the microfacet normals is converted to the density of the directions, then scaled by the
+// probability of reflecting or refracting
+func (m RoughDielectricMaterial) localPDF(wo, wi Vec3, eta float64) float64 {
+	if wo.Z <= 0 || wi.Z == 0 {
+		return 0
+	}
+
+	h, reflection := m.halfVector(wo, wi, eta)
+
+	if h.NearZero() {
+		return 0
+	}
+
+	cosH := wo.Dot(h)
+	reflectance := FresnelDielectric(cosH, eta)
+	pdf := m.distribution.visibleNormalPDF(wo, h)
+
+	if reflection {
+		return pdf * reflectance / (4 * cosH)
+	}
+
+	denominator := wi.Dot(h)*eta + cosH
+	return pdf * (1 - reflectance) * math.Abs(wi.Dot(h)) * eta * eta / (denominator * denominator)
+}
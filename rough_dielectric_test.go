@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestFresnelDielectric(t *testing.T) {
+	// At normal incidence the reflectance is ((eta-1) / (eta+1))²
+	if r, want := FresnelDielectric(1, 1.5), 0.04; math.Abs(r-want) > 1e-9 {
+		t.Errorf("reflectance of glass at normal incidence is %v, want %v", r, want)
+	}
+
+	if r := FresnelDielectric(0, 1.5); math.Abs(r-1) > 1e-9 {
+		t.Errorf("reflectance of glass at a grazing angle is %v, want 1", r)
+	}
+
+	// Total internal reflection beyond the critical angle
+	if r := FresnelDielectric(math.Cos(DegreesToRadians(45)), 1/1.5); r != 1 {
+		t.Errorf("reflectance inside glass at 45 degrees is %v, want 1", r)
+	}
+
+	// The same ray going the other way
+	cosI := math.Cos(DegreesToRadians(30))
+	cosT := math.Sqrt(1 - (1-cosI*cosI)/(1.5*1.5))
+	if a, b := FresnelDielectric(cosI, 1.5), FresnelDielectric(cosT, 1/1.5); math.Abs(a-b) > 1e-9 {
+		t.Errorf("reflectance entering glass is %v, leaving it %v", a, b)
+	}
+}
+
+// Samples must be consistent from both sides of the surface, and most light must enter the glass
+func TestRoughDielectricSampling(t *testing.T) {
+	m := NewRoughDielectricMaterial(1.5, 0.5, 0.3)
+	wo := NewVec3(0.5, 0.2, 0.8).UnitVector()
+
+	for _, frontFace := range []bool{true, false} {
+		rec := HitRecord{P: NewPoint3(0, 0, 0), Normal: NewVec3(0, 0, 1), FrontFace: frontFace}
+		rec.SetTangentFrame(NewVec3(1, 0, 0))
+
+		t.Run(fmt.Sprintf("front face %v", frontFace), func(t *testing.T) {
+			stats := checkSampling(t, m, &rec, wo)
+
+			if frontFace && stats.transmitted < stats.kept/2 {
+				t.Errorf("only %d of %d samples entered the glass", stats.transmitted, stats.kept)
+			}
+		})
+	}
+}
+
+// A surface that is smooth in one direction only is still rough, and must not divide by its zero width
+func TestRoughDielectricSmoothInOneDirection(t *testing.T) {
+	checkSmoothInOneDirection(t, NewRoughDielectricMaterial(1.5, 0, 0.5))
+}
@@ -0,0 +1,937 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Scenes can be described by JSON files, so they can be changed without recompiling the program. For example:
+//
+//	{
+//	  "camera": { "lookFrom": [-2, 2, 1], "lookAt": [0, 0, -1], "vfov": 20 },
+//	  "materials": {
+//	    "ground": { "type": "lambertian", "albedo": [0.8, 0.8, 0.0] },
+//	    "glass": { "type": "dielectric", "ior": 1.5 }
+//	  },
+//	  "objects": [
+//	    { "type": "sphere", "center": [0, -100.5, -1], "radius": 100, "material": "ground" },
+//	    { "type": "sphere", "center": [0, 0, -1], "radius": 0.5, "material": "glass" }
+//	  ]
+//	}
+//
+// All camera parameters are optional, see sceneCamera for the complete list. Paths of the files referenced by the
+// scene (e.g. meshes) are relative to the directory of the scene file.
+type sceneFile struct {
+	Camera    sceneCamera              `json:"camera"`
+	Materials map[string]sceneMaterial `json:"materials"`
+	Objects   []sceneObject            `json:"objects"`
+	Fog       *sceneFog                `json:"fog"`
+}
+
+// Homogeneous fog that fills the whole scene
+type sceneFog struct {
+	Density float64    `json:"density"`
+	Albedo  *sceneVec3 `json:"albedo"` // White by default
+}
+
+// Points, vectors and colors are written as arrays of three numbers
+type sceneVec3 [3]float64
+
+func (v sceneVec3) Vec3() Vec3 {
+	return NewVec3(v[0], v[1], v[2])
+}
+
+type sceneCamera struct {
+	LookFrom        *sceneVec3      `json:"lookFrom"`
+	LookAt          *sceneVec3      `json:"lookAt"`
+	VUp             *sceneVec3      `json:"vUp"`
+	VFov            *float64        `json:"vfov"`
+	DefocusAngle    *float64        `json:"defocusAngle"`
+	FocusDistance   *float64        `json:"focusDistance"`
+	ImageWidth      *int            `json:"imageWidth"`
+	AspectRatio     *float64        `json:"aspectRatio"`
+	SamplesPerPixel *int            `json:"samplesPerPixel"`
+	MaxRayDepth     *int            `json:"maxRayDepth"`
+	Background      json.RawMessage `json:"background"` // See sceneCamera.background()
+	Shutter         *[2]float64     `json:"shutter"`    // Opening and closing times
+}
+
+// The background is either a color or an object describing a gradient or an environment image
+type sceneBackground struct {
+	Type      string     `json:"type"`
+	Bottom    *sceneVec3 `json:"bottom"`    // Gradient
+	Top       *sceneVec3 `json:"top"`       // Gradient
+	File      string     `json:"file"`      // Image
+	Rotation  float64    `json:"rotation"`  // Image, in degrees around the vertical axis
+	Intensity *float64   `json:"intensity"` // Image
+}
+
+type sceneMaterial struct {
+	Type       string          `json:"type"`
+	Albedo     json.RawMessage `json:"albedo"`     // Lambertian and metal, see texture()
+	Fuzz       float64         `json:"fuzz"`       // Metal
+	IOR        *float64        `json:"ior"`        // Dielectric
+	Absorption *sceneVec3      `json:"absorption"` // Smooth dielectric
+	Emit       *sceneVec3      `json:"emit"`       // Diffuse light
+
+	// Conductor, either a preset name or a complex index of refraction
+	Preset string     `json:"preset"`
+	Eta    *sceneVec3 `json:"eta"`
+	K      *sceneVec3 `json:"k"`
+
+	// Conductor, dielectric and principled, where it can be a texture
+	Roughness  json.RawMessage `json:"roughness"`
+	RoughnessV *float64        `json:"roughnessV"` // Defaults to roughness, i.e. an isotropic surface
+
+	// Principled, every parameter is either a number or a texture
+	BaseColor      json.RawMessage `json:"baseColor"`
+	Metallic       json.RawMessage `json:"metallic"`
+	Specular       json.RawMessage `json:"specular"`
+	SpecularTint   json.RawMessage `json:"specularTint"`
+	Sheen          json.RawMessage `json:"sheen"`
+	Clearcoat      json.RawMessage `json:"clearcoat"`
+	ClearcoatGloss json.RawMessage `json:"clearcoatGloss"`
+	Transmission   json.RawMessage `json:"transmission"`
+}
+
+// A texture is either a color or an object describing a checkerboard, an image or a procedural texture
+type sceneTexture struct {
+	Type    string      `json:"type"`
+	Scale   *float64    `json:"scale"`   // Checker and procedural
+	Even    *sceneVec3  `json:"even"`    // Checker
+	Odd     *sceneVec3  `json:"odd"`     // Checker
+	File    string      `json:"file"`    // Image
+	Seed    int64       `json:"seed"`    // Procedural
+	Octaves *int        `json:"octaves"` // Turbulence
+	Ramp    []sceneVec3 `json:"ramp"`    // Procedural, evenly spaced colors
+}
+
+type sceneObject struct {
+	Type     string     `json:"type"`
+	Material string     `json:"material"`
+	Center   *sceneVec3 `json:"center"` // Sphere and disk
+	Radius   *float64   `json:"radius"` // Sphere and disk
+	Normal   *sceneVec3 `json:"normal"` // Plane and disk
+
+	Corner *sceneVec3 `json:"corner"` // Quad
+	U      *sceneVec3 `json:"u"`      // Quad, first edge
+	V      *sceneVec3 `json:"v"`      // Quad, second edge
+	Point  *sceneVec3 `json:"point"`  // Plane
+	Min    *sceneVec3 `json:"min"`    // Box
+	Max    *sceneVec3 `json:"max"`    // Box
+
+	Vertices *[3]sceneVec3 `json:"vertices"` // Triangle
+	Normals  *[3]sceneVec3 `json:"normals"`  // Triangle (optional)
+
+	File string `json:"file"` // Mesh, a Wavefront OBJ file
+
+	Boundary *sceneObject    `json:"boundary"` // Medium, the shape of the volume, which needs no material
+	Density  *float64        `json:"density"`  // Medium
+	Albedo   json.RawMessage `json:"albedo"`   // Medium, white by default
+
+	Transform *sceneTransform `json:"transform"` // Optional, for all objects
+
+	// Moving objects go from their initial position, at the first time, to the final one, at the second time
+	Center1    *sceneVec3      `json:"center1"`    // Sphere, final center
+	Transform1 *sceneTransform `json:"transform1"` // All objects, final transform
+	Times      *[2]float64     `json:"times"`      // Optional, [0, 1] by default
+}
+
+// Objects are placed by scaling them first, then rotating them around the X, Y and Z axes (in this order, by angles
+// in degrees), and finally translating them. For example:
+//
+//	"transform": { "scale": [2, 2, 2], "rotate": [0, 45, 0], "translate": [0, 1, 0] }
+type sceneTransform struct {
+	Scale     *sceneVec3 `json:"scale"`
+	Rotate    *sceneVec3 `json:"rotate"`
+	Translate *sceneVec3 `json:"translate"`
+}
+
+func (t sceneTransform) pose() (Pose, error) {
+	pose := NewPose()
+
+	if t.Scale != nil {
+		pose.Scale = t.Scale.Vec3()
+
+		if pose.Scale.X == 0 || pose.Scale.Y == 0 || pose.Scale.Z == 0 {
+			return pose, errors.New("scale factors cannot be 0")
+		}
+	}
+
+	if t.Rotate != nil {
+		pose.Rotation = t.Rotate.Vec3()
+	}
+
+	if t.Translate != nil {
+		pose.Translation = t.Translate.Vec3()
+	}
+
+	return pose, nil
+}
+
+// Describes a problem found in a scene description (or in a file it refers to, like a mesh), together with its
+// position or the field that caused it
+type SceneError struct {
+	Filename string
+	Line     int // Line and column are zero if the position is not known
+	Column   int
+	Field    string
+	Err      error
+}
+
+func (e *SceneError) Error() string {
+	var b strings.Builder
+
+	b.WriteString(e.Filename)
+
+	if e.Line > 0 {
+		if b.Len() > 0 {
+			b.WriteString(":")
+		}
+		fmt.Fprintf(&b, "%d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ":%d", e.Column)
+		}
+	}
+
+	if b.Len() > 0 {
+		b.WriteString(": ")
+	}
+
+	if e.Field != "" {
+		b.WriteString(e.Field)
+		b.WriteString(": ")
+	}
+
+	b.WriteString(e.Err.Error())
+
+	return b.String()
+}
+
+func (e *SceneError) Unwrap() error {
+	return e.Err
+}
+
+// Loads a scene description file, returning the world and the camera looking at it
+func LoadScene(filename string) (HittableList, PositionableCamera, error) {
+	f, err := os.Open(filename)
+
+	if err != nil {
+		return HittableList{}, PositionableCamera{}, err
+	}
+
+	defer f.Close()
+
+	world, camera, err := readScene(f, filepath.Dir(filename))
+
+	var sceneError *SceneError
+	if errors.As(err, &sceneError) {
+		sceneError.Filename = filename
+	}
+
+	return world, camera, err
+}
+
+// Reads a scene description, errors report the line or the field where the problem was found.
+// Files referenced by the scene are looked for in the current directory.
+func ReadScene(r io.Reader) (HittableList, PositionableCamera, error) {
+	return readScene(r, ".")
+}
+
+// Same as ReadScene, but files referenced by the scene are looked for in dir
+func readScene(r io.Reader, dir string) (HittableList, PositionableCamera, error) {
+	data, err := io.ReadAll(r)
+
+	if err != nil {
+		return HittableList{}, PositionableCamera{}, err
+	}
+
+	scene := sceneFile{}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&scene); err != nil {
+		sceneError := &SceneError{Err: err}
+		offset := dec.InputOffset()
+
+		var syntaxError *json.SyntaxError
+		var typeError *json.UnmarshalTypeError
+
+		if errors.As(err, &syntaxError) {
+			offset = syntaxError.Offset
+		} else if errors.As(err, &typeError) {
+			offset = typeError.Offset
+			sceneError.Field = typeError.Field
+			sceneError.Err = fmt.Errorf("cannot use a JSON %s as %s", typeError.Value, typeError.Type)
+		}
+
+		sceneError.Line, sceneError.Column = lineAndColumn(data, offset)
+
+		return HittableList{}, PositionableCamera{}, sceneError
+	}
+
+	return scene.build(dir)
+}
+
+// Converts a byte offset into 1-based line and column numbers
+func lineAndColumn(data []byte, offset int64) (int, int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	before := data[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := len(before) - bytes.LastIndexByte(before, '\n')
+
+	return line, column
+}
+
+func (scene sceneFile) build(dir string) (HittableList, PositionableCamera, error) {
+	world := NewHittableList()
+	camera, err := scene.Camera.build(dir)
+
+	if err != nil {
+		return world, camera, err
+	}
+
+	materials := map[string]Material{}
+
+	// Sort the names so that errors are always reported in the same order
+	names := make([]string, 0, len(scene.Materials))
+	for name := range scene.Materials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		mat, err := scene.Materials[name].build(dir)
+
+		if err != nil {
+			return world, camera, &SceneError{Field: "materials." + name, Err: err}
+		}
+
+		materials[name] = mat
+	}
+
+	meshes := map[sceneMesh]Hittable{}
+	lights := []Light{}
+
+	for i, o := range scene.Objects {
+		object, err := o.build(materials, dir, meshes)
+
+		if err != nil {
+			return world, camera, &SceneError{Field: fmt.Sprintf("objects[%d]", i), Err: err}
+		}
+
+		world.Add(object)
+
+		// Emissive spheres and quads are sampled as lights, other emissive objects are only found by chance
+		if light, ok := emissiveLight(object); ok {
+			lights = append(lights, light)
+		}
+	}
+
+	camera.SetLights(lights...)
+
+	if scene.Fog != nil {
+		if scene.Fog.Density <= 0 {
+			return world, camera, &SceneError{Field: "fog", Err: errors.New("density must be positive")}
+		}
+
+		camera.SetFog(scene.Fog.Density, valueOrDefault(scene.Fog.Albedo, sceneVec3{1, 1, 1}).Vec3())
+	}
+
+	return world, camera, nil
+}
+
+func (c sceneCamera) build(dir string) (PositionableCamera, error) {
+	camera := NewPositionableCamera()
+
+	if c.LookFrom != nil {
+		camera.SetLookFrom(c.LookFrom.Vec3())
+	}
+
+	if c.LookAt != nil {
+		camera.SetLookAt(c.LookAt.Vec3())
+	}
+
+	if c.VUp != nil {
+		camera.SetViewUp(c.VUp.Vec3())
+	}
+
+	if c.VFov != nil {
+		camera.SetVerticalFieldOfView(*c.VFov)
+	}
+
+	if c.DefocusAngle != nil {
+		camera.SetDefocusAngle(*c.DefocusAngle)
+	}
+
+	if c.FocusDistance != nil {
+		camera.SetFocusDistance(*c.FocusDistance)
+	}
+
+	if c.Shutter != nil {
+		camera.SetShutter(c.Shutter[0], c.Shutter[1])
+	}
+
+	if c.Background != nil {
+		background, err := c.background(dir)
+
+		if err != nil {
+			return camera, &SceneError{Field: "camera.background", Err: err}
+		}
+
+		camera.SetBackground(background)
+	}
+
+	// Rendering parameters are also read from the file, they can still be overridden from the command line
+	RenderOptions{
+		ImageWidth:      valueOrZero(c.ImageWidth),
+		AspectRatio:     valueOrZero(c.AspectRatio),
+		SamplesPerPixel: valueOrZero(c.SamplesPerPixel),
+		MaxRayDepth:     valueOrZero(c.MaxRayDepth),
+	}.Apply(&camera)
+
+	return camera, nil
+}
+
+// The background can be written as:
+//   - a color, e.g. [0, 0, 0];
+//   - a vertical gradient, e.g. { "type": "gradient", "bottom": [1, 1, 1], "top": [0.5, 0.7, 1] };
+//   - an environment image in latitude-longitude format, e.g. { "type": "image", "file": "sky.hdr" }, optionally
+//     with a rotation in degrees around the vertical axis and an intensity scale.
+func (c sceneCamera) background(dir string) (Background, error) {
+	color := sceneVec3{}
+	if json.Unmarshal(c.Background, &color) == nil {
+		return NewSolidBackground(color.Vec3()), nil
+	}
+
+	b := sceneBackground{}
+
+	dec := json.NewDecoder(bytes.NewReader(c.Background))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&b); err != nil {
+		return nil, errors.New("must be either a color or an object with type gradient or image")
+	}
+
+	switch b.Type {
+	case "gradient":
+		if b.Bottom == nil || b.Top == nil {
+			return nil, errors.New("gradient needs both bottom and top colors")
+		}
+
+		return NewGradientBackground(b.Bottom.Vec3(), b.Top.Vec3()), nil
+
+	case "image":
+		if b.File == "" {
+			return nil, errors.New("file is missing")
+		}
+
+		image, err := LoadImage(sceneFilePath(dir, b.File))
+
+		if err != nil {
+			return nil, err
+		}
+
+		environment := NewEnvironmentBackground(image)
+		environment.SetRotation(b.Rotation)
+
+		if b.Intensity != nil {
+			environment.SetIntensity(*b.Intensity)
+		}
+
+		return environment, nil
+
+	case "":
+		return nil, errors.New("type is missing")
+	}
+
+	return nil, fmt.Errorf("unknown type %q, use one of gradient or image", b.Type)
+}
+
+// A texture can be written as:
+//   - a color, e.g. [0.8, 0.8, 0];
+//   - a 3D checkerboard of cubes of the given size, e.g. { "type": "checker", "scale": 0.5, "even": [1, 1, 1], "odd": [0, 0, 0] };
+//   - an image mapped on the surface coordinates, e.g. { "type": "image", "file": "earth.png" };
+//   - a procedural texture of type noise, turbulence, marble, wood or worley, e.g.
+//     { "type": "marble", "scale": 4, "seed": 1, "ramp": [[0.1, 0.1, 0.1], [1, 1, 1]] }, where the ramp lists
+//     evenly spaced colors (black to white by default) and turbulence also takes the number of octaves (7 by default).
+func texture(raw json.RawMessage, dir string) (Texture, error) {
+	color := sceneVec3{}
+	if json.Unmarshal(raw, &color) == nil {
+		return NewSolidColor(color.Vec3()), nil
+	}
+
+	t := sceneTexture{}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(&t); err != nil {
+		return nil, errors.New("must be either a color or an object with type checker, image, noise, turbulence, marble, wood or worley")
+	}
+
+	switch t.Type {
+	case "checker":
+		if t.Scale == nil || *t.Scale <= 0 {
+			return nil, errors.New("checker needs a positive scale")
+		}
+
+		if t.Even == nil || t.Odd == nil {
+			return nil, errors.New("checker needs both even and odd colors")
+		}
+
+		return NewCheckerTextureFromColors(*t.Scale, t.Even.Vec3(), t.Odd.Vec3()), nil
+
+	case "image":
+		if t.File == "" {
+			return nil, errors.New("file is missing")
+		}
+
+		return LoadImageTexture(sceneFilePath(dir, t.File))
+
+	case "noise", "turbulence", "marble", "wood", "worley":
+		return t.procedural()
+
+	case "":
+		return nil, errors.New("type is missing")
+	}
+
+	return nil, fmt.Errorf("unknown type %q, use one of checker, image, noise, turbulence, marble, wood or worley", t.Type)
+}
+
+func (t sceneTexture) procedural() (Texture, error) {
+	scale := valueOrDefault(t.Scale, 1)
+	if scale <= 0 {
+		return nil, errors.New("scale must be positive")
+	}
+
+	ramp := NewEvenColorRamp(NewColor(0, 0, 0), NewColor(1, 1, 1))
+	if len(t.Ramp) > 0 {
+		colors := make([]Color, len(t.Ramp))
+		for i, c := range t.Ramp {
+			colors[i] = c.Vec3()
+		}
+		ramp = NewEvenColorRamp(colors...)
+	}
+
+	switch t.Type {
+	case "noise":
+		return NewNoiseTexture(NewPerlin(t.Seed), scale, ramp), nil
+	case "turbulence":
+		octaves := valueOrDefault(t.Octaves, 7)
+		if octaves <= 0 {
+			return nil, errors.New("octaves must be positive")
+		}
+		return NewTurbulenceTexture(NewPerlin(t.Seed), scale, octaves, ramp), nil
+	case "marble":
+		return NewMarbleTexture(NewPerlin(t.Seed), scale, ramp), nil
+	case "wood":
+		return NewWoodTexture(NewPerlin(t.Seed), scale, ramp), nil
+	}
+
+	return NewWorleyTexture(NewWorley(t.Seed), scale, ramp), nil
+}
+
+// Paths in a scene file are relative to the directory of the file
+func sceneFilePath(dir, filename string) string {
+	if filepath.IsAbs(filename) {
+		return filename
+	}
+
+	return filepath.Join(dir, filename)
+}
+
+func valueOrZero[T any](p *T) T {
+	var v T
+
+	if p != nil {
+		v = *p
+	}
+
+	return v
+}
+
+func (m sceneMaterial) build(dir string) (Material, error) {
+	switch m.Type {
+	case "lambertian":
+		if m.Albedo == nil {
+			return nil, errors.New("albedo is missing")
+		}
+
+		albedo, err := texture(m.Albedo, dir)
+
+		if err != nil {
+			return nil, fmt.Errorf("albedo: %w", err)
+		}
+
+		return NewTexturedLambertianMaterial(albedo), nil
+
+	case "metal":
+		if m.Albedo == nil {
+			return nil, errors.New("albedo is missing")
+		}
+
+		albedo, err := texture(m.Albedo, dir)
+
+		if err != nil {
+			return nil, fmt.Errorf("albedo: %w", err)
+		}
+
+		return NewTexturedMetalMaterial(albedo, m.Fuzz), nil
+
+	case "dielectric":
+		if m.IOR == nil {
+			return nil, errors.New("ior (index of refraction) is missing")
+		}
+
+		roughnessU, roughnessV, err := m.roughness()
+
+		if err != nil {
+			return nil, err
+		}
+
+		if roughnessU > 0 || roughnessV > 0 {
+			if m.Absorption != nil {
+				return nil, errors.New("absorption is only supported by smooth dielectrics")
+			}
+
+			return NewRoughDielectricMaterial(*m.IOR, roughnessU, roughnessV), nil
+		}
+
+		mat := NewDielectricMaterial(*m.IOR)
+
+		if m.Absorption != nil {
+			mat.SetAbsorption(m.Absorption.Vec3())
+		}
+
+		return mat, nil
+
+	case "conductor":
+		ior, err := m.complexIOR()
+
+		if err != nil {
+			return nil, err
+		}
+
+		roughnessU, roughnessV, err := m.roughness()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return NewConductorMaterial(ior, roughnessU, roughnessV), nil
+
+	case "principled":
+		return m.principled(dir)
+
+	case "light":
+		if m.Emit == nil {
+			return nil, errors.New("emit is missing")
+		}
+
+		return NewDiffuseLight(m.Emit.Vec3()), nil
+
+	case "":
+		return nil, errors.New("type is missing")
+	}
+
+	return nil, fmt.Errorf("unknown type %q, use one of lambertian, metal, dielectric, conductor, principled or light", m.Type)
+}
+
+func (m sceneMaterial) roughness() (u, v float64, err error) {
+	if m.Roughness != nil && json.Unmarshal(m.Roughness, &u) != nil {
+		return 0, 0, errors.New("roughness must be a number")
+	}
+
+	return u, valueOrDefault(m.RoughnessV, u), nil
+}
+
+func (m sceneMaterial) principled(dir string) (Material, error) {
+	if m.BaseColor == nil {
+		return nil, errors.New("baseColor is missing")
+	}
+
+	baseColor, err := texture(m.BaseColor, dir)
+
+	if err != nil {
+		return nil, fmt.Errorf("baseColor: %w", err)
+	}
+
+	mat := NewPrincipledMaterial(baseColor)
+
+	parameters := []struct {
+		name string
+		raw  json.RawMessage
+		set  func(Texture)
+	}{
+		{"metallic", m.Metallic, mat.SetMetallic},
+		{"roughness", m.Roughness, mat.SetRoughness},
+		{"specular", m.Specular, mat.SetSpecular},
+		{"specularTint", m.SpecularTint, mat.SetSpecularTint},
+		{"sheen", m.Sheen, mat.SetSheen},
+		{"clearcoat", m.Clearcoat, mat.SetClearcoat},
+		{"clearcoatGloss", m.ClearcoatGloss, mat.SetClearcoatGloss},
+		{"transmission", m.Transmission, mat.SetTransmission},
+	}
+
+	for _, p := range parameters {
+		if p.raw == nil {
+			continue
+		}
+
+		t, err := scalarTexture(p.raw, dir)
+
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", p.name, err)
+		}
+
+		p.set(t)
+	}
+
+	if m.IOR != nil {
+		mat.SetIOR(*m.IOR)
+	}
+
+	return mat, nil
+}
+
+// A scalar parameter is either a number or a texture, whose red channel is used
+func scalarTexture(raw json.RawMessage, dir string) (Texture, error) {
+	x := 0.0
+	if json.Unmarshal(raw, &x) == nil {
+		return NewSolidColor(NewColor(x, x, x)), nil
+	}
+
+	return texture(raw, dir)
+}
+
+var conductorPresets = map[string]ComplexIOR{
+	"gold":      Gold,
+	"copper":    Copper,
+	"aluminium": Aluminium,
+	"silver":    Silver,
+}
+
+func (m sceneMaterial) complexIOR() (ComplexIOR, error) {
+	if m.Preset != "" {
+		if m.Eta != nil || m.K != nil {
+			return ComplexIOR{}, errors.New("use either a preset or eta and k")
+		}
+
+		ior, ok := conductorPresets[m.Preset]
+
+		if !ok {
+			return ComplexIOR{}, fmt.Errorf("unknown preset %q, use one of gold, copper, aluminium or silver", m.Preset)
+		}
+
+		return ior, nil
+	}
+
+	if m.Eta == nil || m.K == nil {
+		return ComplexIOR{}, errors.New("preset or eta and k are missing")
+	}
+
+	return ComplexIOR{Eta: m.Eta.Vec3(), K: m.K.Vec3()}, nil
+}
+
+// Meshes are loaded once per file and material, then shared by all the objects that use them
+type sceneMesh struct {
+	file     string
+	material string
+}
+
+func (o sceneObject) build(materials map[string]Material, dir string, meshes map[sceneMesh]Hittable) (Hittable, error) {
+	if o.Type == "medium" {
+		return o.medium(dir, meshes) // Places its boundary rather than itself, see medium()
+	}
+
+	object, err := o.shape(materials, dir, meshes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return o.place(object)
+}
+
+// Applies the transforms of the object, if any
+func (o sceneObject) place(object Hittable) (Hittable, error) {
+	if o.Transform == nil && o.Transform1 == nil {
+		return object, nil
+	}
+
+	var err error
+	start, end := NewPose(), NewPose()
+
+	if o.Transform != nil {
+		if start, err = o.Transform.pose(); err != nil {
+			return nil, fmt.Errorf("transform: %w", err)
+		}
+	}
+
+	if o.Transform1 == nil {
+		return NewInstance(object, start.Transform()), nil
+	}
+
+	if end, err = o.Transform1.pose(); err != nil {
+		return nil, fmt.Errorf("transform1: %w", err)
+	}
+
+	times := valueOrDefault(o.Times, [2]float64{0, 1})
+
+	return NewMovingInstance(object, start, end, times[0], times[1]), nil
+}
+
+func (o sceneObject) shape(materials map[string]Material, dir string, meshes map[sceneMesh]Hittable) (Hittable, error) {
+	mat, found := materials[o.Material]
+
+	if !found {
+		if o.Material != "" {
+			return nil, fmt.Errorf("material %q is not defined", o.Material)
+		}
+
+		if o.Type != "mesh" { // Meshes may use the materials of their own file
+			return nil, errors.New("material is missing")
+		}
+	}
+
+	switch o.Type {
+	case "sphere":
+		if o.Center == nil {
+			return nil, errors.New("center is missing")
+		}
+
+		if o.Radius == nil {
+			return nil, errors.New("radius is missing")
+		}
+
+		if o.Center1 != nil {
+			times := valueOrDefault(o.Times, [2]float64{0, 1})
+			return NewMovingSphere(o.Center.Vec3(), o.Center1.Vec3(), times[0], times[1], *o.Radius, mat), nil
+		}
+
+		return NewSphereWithMaterial(o.Center.Vec3(), *o.Radius, mat), nil
+
+	case "triangle":
+		if o.Vertices == nil {
+			return nil, errors.New("vertices are missing")
+		}
+
+		v := o.Vertices
+
+		if o.Normals != nil {
+			n := o.Normals
+			return NewTriangleWithNormals(v[0].Vec3(), v[1].Vec3(), v[2].Vec3(), n[0].Vec3(), n[1].Vec3(), n[2].Vec3(), mat), nil
+		}
+
+		return NewTriangle(v[0].Vec3(), v[1].Vec3(), v[2].Vec3(), mat), nil
+
+	case "quad":
+		if o.Corner == nil || o.U == nil || o.V == nil {
+			return nil, errors.New("quad needs corner, u and v")
+		}
+
+		return NewQuad(o.Corner.Vec3(), o.U.Vec3(), o.V.Vec3(), mat), nil
+
+	case "plane":
+		if o.Point == nil || o.Normal == nil {
+			return nil, errors.New("plane needs point and normal")
+		}
+
+		return NewPlane(o.Point.Vec3(), o.Normal.Vec3(), mat), nil
+
+	case "disk":
+		if o.Center == nil || o.Normal == nil || o.Radius == nil {
+			return nil, errors.New("disk needs center, normal and radius")
+		}
+
+		return NewDisk(o.Center.Vec3(), o.Normal.Vec3(), *o.Radius, mat), nil
+
+	case "box":
+		if o.Min == nil || o.Max == nil {
+			return nil, errors.New("box needs min and max")
+		}
+
+		return NewBox(o.Min.Vec3(), o.Max.Vec3(), mat), nil
+
+	case "mesh":
+		if o.File == "" {
+			return nil, errors.New("file is missing")
+		}
+
+		key := sceneMesh{file: sceneFilePath(dir, o.File), material: o.Material}
+
+		if mesh, found := meshes[key]; found {
+			return mesh, nil
+		}
+
+		mesh, err := LoadOBJ(key.file, mat)
+
+		if err != nil {
+			return nil, err
+		}
+
+		meshes[key] = mesh
+
+		return mesh, nil
+
+	case "":
+		return nil, errors.New("type is missing")
+	}
+
+	return nil, fmt.Errorf("unknown type %q, use one of sphere, triangle, quad, plane, disk, box, mesh or medium", o.Type)
+}
+
+func (o sceneObject) medium(dir string, meshes map[sceneMesh]Hittable) (Hittable, error) {
+	if o.Boundary == nil {
+		return nil, errors.New("boundary is missing")
+	}
+
+	if o.Density == nil || *o.Density <= 0 {
+		return nil, errors.New("density must be positive")
+	}
+
+	albedo := Texture(NewSolidColor(NewColor(1, 1, 1)))
+
+	if o.Albedo != nil {
+		var err error
+
+		if albedo, err = texture(o.Albedo, dir); err != nil {
+			return nil, fmt.Errorf("albedo: %w", err)
+		}
+	}
+
+	// The boundary is only used to tell the inside from the outside, so it doesn't need a material
+	boundary, err := o.Boundary.build(map[string]Material{"": nil}, dir, meshes)
+
+	if err != nil {
+		return nil, fmt.Errorf("boundary: %w", err)
+	}
+
+	// The medium measures distances along the rays it receives, so it must see them in world space: transforming the
+	// medium instead of its boundary would make a scaled medium thinner or denser
+	if boundary, err = o.place(boundary); err != nil {
+		return nil, err
+	}
+
+	return NewTexturedConstantMedium(boundary, *o.Density, albedo), nil
+}
+
+func valueOrDefault[T any](p *T, defaultValue T) T {
+	if p != nil {
+		return *p
+	}
+
+	return defaultValue
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadScene(t *testing.T) {
+	world, cam, err := ReadScene(strings.NewReader(`{
+		"camera": { "lookFrom": [-2, 2, 1], "vfov": 20, "imageWidth": 100 },
+		"materials": { "glass": { "type": "dielectric", "ior": 1.5 } },
+		"objects": [ { "type": "sphere", "center": [0, 0, -1], "radius": 0.5, "material": "glass" } ]
+	}`))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(world.objects) != 1 {
+		t.Errorf("got %d objects, want 1", len(world.objects))
+	}
+
+	if cam.vfov != 20 || cam.imageWidth != 100 || cam.lookFrom != NewPoint3(-2, 2, 1) || cam.lookAt != NewPoint3(0, 0, -1) {
+		t.Errorf("camera mismatch: %+v", cam)
+	}
+}
+
+func TestReadSceneErrors(t *testing.T) {
+	tests := []struct {
+		scene string
+		line  int
+		want  string
+	}{
+		{"{\n\"objects\": [,]}", 2, "invalid character"},
+		{"{\n\"camera\": { \"vfov\": \"wide\" }}", 2, "camera.vfov: cannot use a JSON string as float64"},
+		{"{\n\n\"camera\": { \"fov\": 20 }}", 3, `json: unknown field "fov"`},
+		{`{"materials": { "m": { "type": "metal" } }}`, 0, "materials.m: albedo is missing"},
+		{`{"materials": { "m": { "type": "wood" } }}`, 0, `materials.m: unknown type "wood"`},
+		{`{"materials": { "m": { "type": "conductor", "preset": "brass" } }}`, 0, `materials.m: unknown preset "brass"`},
+		{`{"materials": { "m": { "type": "conductor", "eta": [1, 1, 1] } }}`, 0, "materials.m: preset or eta and k are missing"},
+		{`{"materials": { "m": { "type": "dielectric", "ior": 1.5, "roughness": 0.5, "absorption": [1, 1, 1] } }}`, 0, "materials.m: absorption is only supported by smooth dielectrics"},
+		{`{"materials": { "m": { "type": "conductor", "preset": "gold", "roughness": [0.5] } }}`, 0, "materials.m: roughness must be a number"},
+		{`{"materials": { "m": { "type": "principled", "baseColor": [1, 0, 0], "sheen": "soft" } }}`, 0, "materials.m: sheen: must be either a color"},
+		{`{"materials": { "m": { "type": "lambertian", "albedo": { "type": "checker", "even": [1, 1, 1], "odd": [0, 0, 0] } } }}`, 0, "materials.m: albedo: checker needs a positive scale"},
+		{`{"objects": [ {}, { "type": "sphere", "material": "m" } ]}`, 0, "objects[0]: material is missing"},
+		{`{"materials": { "m": { "type": "dielectric", "ior": 1.5 } }, "objects": [ { "type": "sphere", "center": [0, 0, 0], "radius": 1, "material": "m", "transform": { "scale": [1, 0, 1] } } ]}`, 0, "objects[0]: transform: scale factors cannot be 0"},
+		{`{"objects": [ { "type": "sphere", "material": "m" } ]}`, 0, `objects[0]: material "m" is not defined`},
+		{`{"objects": [ { "type": "medium", "density": 1, "boundary": { "type": "sphere", "radius": 1 } } ]}`, 0, "objects[0]: boundary: center is missing"},
+		{`{"objects": [ { "type": "medium", "boundary": { "type": "sphere", "center": [0, 0, 0], "radius": 1 } } ]}`, 0, "objects[0]: density must be positive"},
+		{`{"fog": { "density": 0 }}`, 0, "fog: density must be positive"},
+	}
+
+	for _, test := range tests {
+		_, _, err := ReadScene(strings.NewReader(test.scene))
+
+		var sceneError *SceneError
+		if !errors.As(err, &sceneError) || sceneError.Line != test.line || !strings.Contains(err.Error(), test.want) {
+			t.Errorf("got error %q, want %q at line %d", err, test.want, test.line)
+		}
+	}
+}
@@ -18,10 +18,14 @@ func NewSphereWithMaterial(center Point3, radius float64, mat Material) Sphere {
 
 // Implement the Hittable interface
 func (s Sphere) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
-	oc := ray.Origin().Sub(s.center)
+	return hitSphere(s.center, s.radius, s.mat, ray, rayTmin, rayTmax, rec)
+}
+
+func hitSphere(center Point3, radius float64, mat Material, ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	oc := ray.Origin().Sub(center)
 	a := ray.Direction().Dot(ray.Direction())
 	half_b := oc.Dot(ray.Direction()) // With respect to the initial versions we have now removed the 2 factor from b and simplified the rest accordingly
-	c := oc.Dot(oc) - s.radius*s.radius
+	c := oc.Dot(oc) - radius*radius
 	discriminant := half_b*half_b - a*c
 
 	if discriminant < 0 {
@@ -40,9 +44,63 @@ func (s Sphere) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
 
 	rec.T = root
 	rec.P = ray.At(rec.T)
-	outwardNormal := rec.P.Sub(s.center).Div(s.radius) // Divide by the sphere radius as it's cheaper that calling UnitVector() and gets the same result here
+	outwardNormal := rec.P.Sub(center).Div(radius) // Divide by the sphere radius as it's cheaper that calling UnitVector() and gets the same result here
 	rec.SetFaceNormal(ray, outwardNormal)
-	rec.Mat = s.mat
+	rec.Mat = mat
+
+	// Hollow spheres have a negative radius, which flips the normal but must not mirror the surface coordinates
+	p := rec.P.Sub(center).Div(math.Abs(radius))
+	rec.U, rec.V = sphereUV(p)
+	rec.SetTangentFrame(NewVec3(p.Z, 0, -p.X)) // Derivative of p with respect to the longitude
 
 	return true
 }
+
+// Returns the surface coordinates of a point p on the unit sphere centered at the origin:
+// U is the longitude, from 0 at -X through +Z, +X and -Z back to -X, and V is the latitude, from 0 at -Y to 1 at +Y.
+// For example (1, 0, 0) is at (0.5, 0.5), (0, 1, 0) at (0.5, 1) and (0, 0, 1) at (0.25, 0.5).
+func sphereUV(p Point3) (u, v float64) {
+	theta := math.Acos(math.Max(-1, math.Min(1, -p.Y)))
+	phi := math.Atan2(-p.Z, p.X) + math.Pi
+
+	return phi / (2 * math.Pi), theta / math.Pi
+}
+
+func (s Sphere) BoundingBox() AABB {
+	r := math.Abs(s.radius) // Hollow spheres have a negative radius
+	rvec := NewVec3(r, r, r)
+	return NewAABBFromPoints(s.center.Sub(rvec), s.center.Add(rvec))
+}
+
+// A sphere that moves in a straight line, from center0 at time0 to center1 at time1. Outside of that interval it
+// stays still at either end, so that it never leaves its bounding box.
+type MovingSphere struct {
+	center0, center1 Point3
+	time0, time1     float64
+	radius           float64
+	mat              Material
+}
+
+func NewMovingSphere(center0, center1 Point3, time0, time1, radius float64, mat Material) MovingSphere {
+	return MovingSphere{center0: center0, center1: center1, time0: time0, time1: time1, radius: radius, mat: mat}
+}
+
+// Returns the position of the center at the given time
+func (s MovingSphere) Center(time float64) Point3 {
+	if s.time1 == s.time0 {
+		return s.center0
+	}
+
+	alpha := math.Max(0, math.Min(1, (time-s.time0)/(s.time1-s.time0)))
+	return s.center0.Add(s.center1.Sub(s.center0).Mul(alpha))
+}
+
+// Implement the Hittable interface
+func (s MovingSphere) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	return hitSphere(s.Center(ray.Time()), s.radius, s.mat, ray, rayTmin, rayTmax, rec)
+}
+
+// The box encloses the sphere along its whole path
+func (s MovingSphere) BoundingBox() AABB {
+	return NewSurroundingAABB(NewSphere(s.center0, s.radius).BoundingBox(), NewSphere(s.center1, s.radius).BoundingBox())
+}
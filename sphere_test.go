@@ -0,0 +1,81 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSphereUV(t *testing.T) {
+	tests := []struct {
+		direction Vec3
+		u, v      float64
+		tangent   Vec3
+	}{
+		{NewVec3(1, 0, 0), 0.5, 0.5, NewVec3(0, 0, -1)},
+		{NewVec3(-1, 0, 0), 1, 0.5, NewVec3(0, 0, 1)}, // On the seam, where U wraps around from 1 to 0
+		{NewVec3(0, 0, 1), 0.25, 0.5, NewVec3(1, 0, 0)},
+		{NewVec3(0, 0, -1), 0.75, 0.5, NewVec3(-1, 0, 0)},
+		{NewVec3(0, 1, 0), 0.5, 1, NewVec3(1, 0, 0)}, // At the poles any tangent is fine, this is the fallback
+		{NewVec3(0, -1, 0), 0.5, 0, NewVec3(1, 0, 0)},
+	}
+
+	center := NewPoint3(1, 2, 3)
+
+	// Hollow spheres must have the same coordinates and tangents, seen from the inside
+	for _, radius := range []float64{2, -2} {
+		s := NewSphere(center, radius)
+
+		for _, test := range tests {
+			// Shoot the ray from the center, so that it hits the sphere from the inside
+			rec := HitRecord{}
+			if !s.Hit(NewRay(center, test.direction), 0.001, math.Inf(+1), &rec) {
+				t.Fatalf("radius %v: ray towards %v should hit the sphere", radius, test.direction)
+			}
+
+			if math.Abs(rec.U-test.u) > 1e-9 && !(test.u == 1 && rec.U < 1e-9) || math.Abs(rec.V-test.v) > 1e-9 {
+				t.Errorf("radius %v: towards %v got (u, v) = (%v, %v), want (%v, %v)", radius, test.direction, rec.U, rec.V, test.u, test.v)
+			}
+
+			if rec.Tangent.Sub(test.tangent).Length() > 1e-9 {
+				t.Errorf("radius %v: towards %v got tangent %v, want %v", radius, test.direction, rec.Tangent, test.tangent)
+			}
+
+			// The frame must be orthonormal
+			if math.Abs(rec.Tangent.Dot(rec.Normal)) > 1e-9 || math.Abs(rec.Bitangent.Length()-1) > 1e-9 || rec.Bitangent != rec.Normal.Cross(rec.Tangent) {
+				t.Errorf("radius %v: towards %v got a bad tangent frame %v, %v, %v", radius, test.direction, rec.Normal, rec.Tangent, rec.Bitangent)
+			}
+		}
+	}
+}
+
+func TestMovingSphere(t *testing.T) {
+	s := NewMovingSphere(NewPoint3(0, 0, -2), NewPoint3(4, 0, -2), 0, 1, 0.5, nil)
+
+	// At time 0 the sphere is in front of the origin, at time 1 it has moved away
+	tests := []struct {
+		time float64
+		hit  bool
+	}{
+		{-1, true}, // Before the motion starts the sphere stays at the initial position
+		{0, true},
+		{0.5, false},
+		{1, false},
+	}
+
+	for _, test := range tests {
+		rec := HitRecord{}
+		if hit := s.Hit(NewRayWithTime(NewPoint3(0, 0, 0), NewVec3(0, 0, -1), test.time), 0.001, math.Inf(+1), &rec); hit != test.hit {
+			t.Errorf("at time %v got hit %v, want %v", test.time, hit, test.hit)
+		}
+	}
+
+	rec := HitRecord{}
+	if !s.Hit(NewRayWithTime(NewPoint3(2, 0, 0), NewVec3(0, 0, -1), 0.5), 0.001, math.Inf(+1), &rec) || rec.P != NewPoint3(2, 0, -1.5) {
+		t.Errorf("at time 0.5 the sphere should be hit at (2, 0, -1.5), got %v", rec.P)
+	}
+
+	box := s.BoundingBox()
+	if box.X != NewInterval(-0.5, 4.5) || box.Z != NewInterval(-2.5, -1.5) {
+		t.Errorf("bounding box %v doesn't enclose the whole motion", box)
+	}
+}
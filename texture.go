@@ -0,0 +1,87 @@
+package main
+
+import "math"
+
+// A texture gives the color of a surface at every point, so that materials don't need to be uniform
+type Texture interface {
+	// Returns the color at the point p, whose surface coordinates are (u, v)
+	Value(u, v float64, p Point3) Color
+}
+
+// A texture of a uniform color
+type SolidColor struct {
+	color Color
+}
+
+func NewSolidColor(c Color) SolidColor {
+	return SolidColor{color: c}
+}
+
+func (t SolidColor) Value(u, v float64, p Point3) Color {
+	return t.color
+}
+
+// A solid gray texture, handy for parameters that are read from a single channel
+func gray(x float64) Texture {
+	return NewSolidColor(NewColor(x, x, x))
+}
+
+// A checkerboard in 3D space: the space is divided in cubes of the given size, which alternate between the even and
+// odd textures. Since it doesn't depend on the surface coordinates, it also works on surfaces that have none.
+type CheckerTexture struct {
+	invScale float64
+	even     Texture
+	odd      Texture
+}
+
+func NewCheckerTexture(scale float64, even, odd Texture) CheckerTexture {
+	return CheckerTexture{invScale: 1 / scale, even: even, odd: odd}
+}
+
+func NewCheckerTextureFromColors(scale float64, even, odd Color) CheckerTexture {
+	return NewCheckerTexture(scale, NewSolidColor(even), NewSolidColor(odd))
+}
+
+func (t CheckerTexture) Value(u, v float64, p Point3) Color {
+	x := int(math.Floor(t.invScale * p.X))
+	y := int(math.Floor(t.invScale * p.Y))
+	z := int(math.Floor(t.invScale * p.Z))
+
+	if (x+y+z)%2 == 0 {
+		return t.even.Value(u, v, p)
+	}
+
+	return t.odd.Value(u, v, p)
+}
+
+// A texture that maps an image on the surface coordinates: (0, 0) is the bottom left corner of the image, (1, 1) the
+// top right one
+type ImageTexture struct {
+	image *Framebuffer
+}
+
+func NewImageTexture(image *Framebuffer) ImageTexture {
+	return ImageTexture{image: image}
+}
+
+// Loads the image of the texture from a file, see LoadImage() for the supported formats
+func LoadImageTexture(filename string) (ImageTexture, error) {
+	image, err := LoadImage(filename)
+
+	if err != nil {
+		return ImageTexture{}, err
+	}
+
+	return NewImageTexture(image), nil
+}
+
+func (t ImageTexture) Value(u, v float64, p Point3) Color {
+	// Coordinates outside of the [0,1] range are clamped, and V is flipped since image rows go from top to bottom
+	u = math.Max(0, math.Min(1, u))
+	v = 1 - math.Max(0, math.Min(1, v))
+
+	x := clampInt(int(u*float64(t.image.Width())), 0, t.image.Width()-1)
+	y := clampInt(int(v*float64(t.image.Height())), 0, t.image.Height()-1)
+
+	return t.image.At(x, y)
+}
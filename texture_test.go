@@ -0,0 +1,80 @@
+package main
+
+import "testing"
+
+func TestCheckerTexture(t *testing.T) {
+	white, black := NewColor(1, 1, 1), NewColor(0, 0, 0)
+	checker := NewCheckerTextureFromColors(0.5, white, black)
+
+	tests := []struct {
+		p    Point3
+		want Color
+	}{
+		{NewPoint3(0.1, 0.1, 0.1), white},
+		{NewPoint3(0.6, 0.1, 0.1), black},
+		{NewPoint3(0.6, 0.6, 0.1), white},
+		{NewPoint3(-0.1, 0.1, 0.1), black}, // Cubes on the negative side continue the pattern
+		{NewPoint3(-0.1, -0.1, 0.1), white},
+		{NewPoint3(-0.1, -0.1, -0.1), black},
+	}
+
+	for _, test := range tests {
+		if got := checker.Value(0, 0, test.p); got != test.want {
+			t.Errorf("checker at %v = %v, want %v", test.p, got, test.want)
+		}
+	}
+}
+
+func TestImageTexture(t *testing.T) {
+	image := NewFramebuffer(2, 2)
+	image.Set(0, 0, NewColor(1, 0, 0)) // Top left
+	image.Set(1, 0, NewColor(0, 1, 0))
+	image.Set(0, 1, NewColor(0, 0, 1)) // Bottom left
+	image.Set(1, 1, NewColor(1, 1, 1))
+
+	texture := NewImageTexture(image)
+
+	tests := []struct {
+		u, v float64
+		want Color
+	}{
+		{0.25, 0.75, NewColor(1, 0, 0)},
+		{0.75, 0.75, NewColor(0, 1, 0)},
+		{0.25, 0.25, NewColor(0, 0, 1)},
+		{1, 0, NewColor(1, 1, 1)},  // Edges are part of the image
+		{-3, 2, NewColor(1, 0, 0)}, // Coordinates outside of the image are clamped
+	}
+
+	for _, test := range tests {
+		if got := texture.Value(test.u, test.v, Point3{}); got != test.want {
+			t.Errorf("texture at (%v, %v) = %v, want %v", test.u, test.v, got, test.want)
+		}
+	}
+}
+
+func TestColorRamp(t *testing.T) {
+	red, green, blue := NewColor(1, 0, 0), NewColor(0, 1, 0), NewColor(0, 0, 1)
+	ramp := NewColorRamp(ColorStop{1, blue}, ColorStop{0, red}, ColorStop{0.5, green})
+
+	tests := []struct {
+		t    float64
+		want Color
+	}{
+		{-1, red},
+		{0, red},
+		{0.25, NewColor(0.5, 0.5, 0)},
+		{0.5, green},
+		{0.75, NewColor(0, 0.5, 0.5)},
+		{2, blue},
+	}
+
+	for _, test := range tests {
+		if got := ramp.At(test.t); got != test.want {
+			t.Errorf("ramp at %v = %v, want %v", test.t, got, test.want)
+		}
+	}
+
+	if got := NewEvenColorRamp(red, green, blue).At(0.75); got != NewColor(0, 0.5, 0.5) {
+		t.Errorf("even ramp at 0.75 = %v", got)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"errors"
+	"math"
+)
+
+// A 4x4 matrix in row-major order. Points and vectors are treated as column vectors in homogeneous coordinates, so
+// the matrix m transforms a point p into m*p.
+type Matrix4 [4][4]float64
+
+func IdentityMatrix4() Matrix4 {
+	return Matrix4{
+		{1, 0, 0, 0},
+		{0, 1, 0, 0},
+		{0, 0, 1, 0},
+		{0, 0, 0, 1}}
+}
+
+// Returns the product m*n, which applies n first and then m
+func (m Matrix4) Mul(n Matrix4) Matrix4 {
+	r := Matrix4{}
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			for k := 0; k < 4; k++ {
+				r[i][j] += m[i][k] * n[k][j]
+			}
+		}
+	}
+
+	return r
+}
+
+func (m Matrix4) Transpose() Matrix4 {
+	r := Matrix4{}
+
+	for i := 0; i < 4; i++ {
+		for j := 0; j < 4; j++ {
+			r[i][j] = m[j][i]
+		}
+	}
+
+	return r
+}
+
+// Returns the inverse of an affine matrix, i.e. one whose last row is (0, 0, 0, 1).
+// The matrix is made of a linear part A (the upper left 3x3 block) and a translation t, its inverse is made of A⁻¹
+// and -A⁻¹t.
+func (m Matrix4) InverseAffine() (Matrix4, error) {
+	// Cofactors of the linear part
+	c00 := m[1][1]*m[2][2] - m[1][2]*m[2][1]
+	c01 := m[1][2]*m[2][0] - m[1][0]*m[2][2]
+	c02 := m[1][0]*m[2][1] - m[1][1]*m[2][0]
+
+	det := m[0][0]*c00 + m[0][1]*c01 + m[0][2]*c02
+
+	if math.Abs(det) < 1e-12 {
+		return Matrix4{}, errors.New("the transformation is not invertible")
+	}
+
+	inv := Matrix4{
+		{c00, m[0][2]*m[2][1] - m[0][1]*m[2][2], m[0][1]*m[1][2] - m[0][2]*m[1][1], 0},
+		{c01, m[0][0]*m[2][2] - m[0][2]*m[2][0], m[0][2]*m[1][0] - m[0][0]*m[1][2], 0},
+		{c02, m[0][1]*m[2][0] - m[0][0]*m[2][1], m[0][0]*m[1][1] - m[0][1]*m[1][0], 0},
+		{0, 0, 0, 1}}
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			inv[i][j] /= det
+		}
+	}
+
+	for i := 0; i < 3; i++ {
+		inv[i][3] = -(inv[i][0]*m[0][3] + inv[i][1]*m[1][3] + inv[i][2]*m[2][3])
+	}
+
+	return inv, nil
+}
+
+// An affine transformation (any combination of translations, rotations and scalings), which keeps its inverse
+// at hand since transforming rays needs it
+type Transform struct {
+	m   Matrix4
+	inv Matrix4
+}
+
+// Returns the transformation of an affine matrix, or an error if the matrix cannot be inverted (e.g. a scaling by 0)
+func NewTransform(m Matrix4) (Transform, error) {
+	inv, err := m.InverseAffine()
+
+	if err != nil {
+		return Transform{}, err
+	}
+
+	return Transform{m: m, inv: inv}, nil
+}
+
+func NewIdentityTransform() Transform {
+	return Transform{m: IdentityMatrix4(), inv: IdentityMatrix4()}
+}
+
+func NewTranslation(offset Vec3) Transform {
+	m, inv := IdentityMatrix4(), IdentityMatrix4()
+	m[0][3], m[1][3], m[2][3] = offset.X, offset.Y, offset.Z
+	inv[0][3], inv[1][3], inv[2][3] = -offset.X, -offset.Y, -offset.Z
+
+	return Transform{m: m, inv: inv}
+}
+
+// Scales by the given factors along the axes, none of them can be 0.
+// Negative factors mirror the object, turning its surfaces inside out.
+func NewScaling(x, y, z float64) Transform {
+	m, inv := IdentityMatrix4(), IdentityMatrix4()
+	m[0][0], m[1][1], m[2][2] = x, y, z
+	inv[0][0], inv[1][1], inv[2][2] = 1/x, 1/y, 1/z
+
+	return Transform{m: m, inv: inv}
+}
+
+// Rotates counterclockwise around the axis by the given angle in degrees, as seen with the axis pointing to the viewer
+func NewRotation(axis Vec3, degrees float64) Transform {
+	a := axis.UnitVector()
+	sin, cos := math.Sincos(DegreesToRadians(degrees))
+
+	// Rodrigues' rotation formula
+	m := IdentityMatrix4()
+	m[0][0], m[0][1], m[0][2] = cos+a.X*a.X*(1-cos), a.X*a.Y*(1-cos)-a.Z*sin, a.X*a.Z*(1-cos)+a.Y*sin
+	m[1][0], m[1][1], m[1][2] = a.Y*a.X*(1-cos)+a.Z*sin, cos+a.Y*a.Y*(1-cos), a.Y*a.Z*(1-cos)-a.X*sin
+	m[2][0], m[2][1], m[2][2] = a.Z*a.X*(1-cos)-a.Y*sin, a.Z*a.Y*(1-cos)+a.X*sin, cos+a.Z*a.Z*(1-cos)
+
+	// The inverse of a rotation is its transpose
+	return Transform{m: m, inv: m.Transpose()}
+}
+
+func NewRotationX(degrees float64) Transform {
+	return NewRotation(NewVec3(1, 0, 0), degrees)
+}
+
+func NewRotationY(degrees float64) Transform {
+	return NewRotation(NewVec3(0, 1, 0), degrees)
+}
+
+func NewRotationZ(degrees float64) Transform {
+	return NewRotation(NewVec3(0, 0, 1), degrees)
+}
+
+// Returns the transformation that applies t first and then next, e.g.
+// NewScaling(2, 2, 2).Then(NewRotationY(45)).Then(NewTranslation(offset))
+func (t Transform) Then(next Transform) Transform {
+	return Transform{m: next.m.Mul(t.m), inv: t.inv.Mul(next.inv)}
+}
+
+func (t Transform) Inverse() Transform {
+	return Transform{m: t.inv, inv: t.m}
+}
+
+func (t Transform) Matrix() Matrix4 {
+	return t.m
+}
+
+func (t Transform) Point(p Point3) Point3 {
+	return transformPoint(&t.m, p)
+}
+
+// Vectors, unlike points, are not affected by translations
+func (t Transform) Vector(v Vec3) Vec3 {
+	return transformVector(&t.m, v)
+}
+
+// Normals must stay perpendicular to the surface, which takes the transpose of the inverse matrix.
+// Note: the result doesn't have unit length in general
+func (t Transform) Normal(n Vec3) Vec3 {
+	m := &t.inv
+	return NewVec3(
+		m[0][0]*n.X+m[1][0]*n.Y+m[2][0]*n.Z,
+		m[0][1]*n.X+m[1][1]*n.Y+m[2][1]*n.Z,
+		m[0][2]*n.X+m[1][2]*n.Y+m[2][2]*n.Z)
+}
+
+// Returns a box enclosing the transformed box, which is the box of its transformed corners
+func (t Transform) Box(box AABB) AABB {
+	if box.IsInfinite() {
+		return NewAABB(Universe, Universe, Universe) // Transforming infinite corners would give NaNs
+	}
+
+	result := EmptyAABB
+
+	for _, x := range []float64{box.X.Min, box.X.Max} {
+		for _, y := range []float64{box.Y.Min, box.Y.Max} {
+			for _, z := range []float64{box.Z.Min, box.Z.Max} {
+				p := t.Point(NewPoint3(x, y, z))
+				result = NewSurroundingAABB(result, NewAABBFromPoints(p, p))
+			}
+		}
+	}
+
+	return result
+}
+
+func transformPoint(m *Matrix4, p Point3) Point3 {
+	return NewPoint3(
+		m[0][0]*p.X+m[0][1]*p.Y+m[0][2]*p.Z+m[0][3],
+		m[1][0]*p.X+m[1][1]*p.Y+m[1][2]*p.Z+m[1][3],
+		m[2][0]*p.X+m[2][1]*p.Y+m[2][2]*p.Z+m[2][3])
+}
+
+func transformVector(m *Matrix4, v Vec3) Vec3 {
+	return NewVec3(
+		m[0][0]*v.X+m[0][1]*v.Y+m[0][2]*v.Z,
+		m[1][0]*v.X+m[1][1]*v.Y+m[1][2]*v.Z,
+		m[2][0]*v.X+m[2][1]*v.Y+m[2][2]*v.Z)
+}
+
+// An instance places an object in the scene with a transformation, so that the same object (e.g. a large mesh) can
+// appear many times without copying it. Rays are transformed into the space of the object, and the hit record back
+// into the space of the scene.
+type Instance struct {
+	object    Hittable
+	transform Transform
+	box       AABB
+}
+
+func NewInstance(object Hittable, transform Transform) Instance {
+	return Instance{object: object, transform: transform, box: transform.Box(object.BoundingBox())}
+}
+
+// Implement the Hittable interface
+func (instance Instance) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	// The direction is not normalized, so that t is the same in both spaces
+	objectRay := NewRayWithTime(transformPoint(&instance.transform.inv, ray.Origin()), transformVector(&instance.transform.inv, ray.Direction()), ray.Time())
+
+	if !instance.object.Hit(objectRay, rayTmin, rayTmax, rec) {
+		return false
+	}
+
+	// The front face doesn't change, since the dot product of the transformed normal and direction keeps its sign
+	rec.P = instance.transform.Point(rec.P)
+	rec.Normal = instance.transform.Normal(rec.Normal).UnitVector()
+	rec.SetTangentFrame(instance.transform.Vector(rec.Tangent))
+
+	return true
+}
+
+func (instance Instance) BoundingBox() AABB {
+	return instance.box
+}
+
+// A pose places an object by scaling it first, then rotating it around the X, Y and Z axes (in this order, by angles in
+// degrees), and finally translating it. Unlike a matrix, two poses can be blended to animate an object.
+type Pose struct {
+	Scale       Vec3 // None of the factors can be 0
+	Rotation    Vec3
+	Translation Vec3
+}
+
+// Returns the pose that leaves objects as they are
+func NewPose() Pose {
+	return Pose{Scale: NewVec3(1, 1, 1)}
+}
+
+func (p Pose) Transform() Transform {
+	return NewScaling(p.Scale.X, p.Scale.Y, p.Scale.Z).
+		Then(NewRotationX(p.Rotation.X)).
+		Then(NewRotationY(p.Rotation.Y)).
+		Then(NewRotationZ(p.Rotation.Z)).
+		Then(NewTranslation(p.Translation))
+}
+
+// Returns the pose at alpha along the way from p (at 0) to q (at 1), interpolating each parameter linearly
+func (p Pose) Lerp(q Pose, alpha float64) Pose {
+	blend := func(a, b Vec3) Vec3 {
+		return a.Add(b.Sub(a).Mul(alpha))
+	}
+
+	return Pose{Scale: blend(p.Scale, q.Scale), Rotation: blend(p.Rotation, q.Rotation), Translation: blend(p.Translation, q.Translation)}
+}
+
+// Number of poses along the motion of a MovingInstance whose boxes are merged into its bounding box
+const movingInstanceBoxSteps = 64
+
+// An instance that moves, e.g. a spinning or flying object: its pose goes from start at time0 to end at time1.
+// Outside of that interval it stays still at either end.
+type MovingInstance struct {
+	object       Hittable
+	start, end   Pose
+	time0, time1 float64
+	box          AABB
+}
+
+func NewMovingInstance(object Hittable, start, end Pose, time0, time1 float64) MovingInstance {
+	instance := MovingInstance{object: object, start: start, end: end, time0: time0, time1: time1, box: EmptyAABB}
+	objectBox := object.BoundingBox()
+
+	// The box of the motion is approximated by the boxes at many poses along the way: since rotations move the corners
+	// along arcs, which may bulge slightly out of the boxes, the result is padded by 1%
+	for i := 0; i <= movingInstanceBoxSteps; i++ {
+		pose := start.Lerp(end, float64(i)/movingInstanceBoxSteps)
+		instance.box = NewSurroundingAABB(instance.box, pose.Transform().Box(objectBox))
+	}
+
+	if !instance.box.IsInfinite() {
+		diagonal := NewVec3(instance.box.X.Size(), instance.box.Y.Size(), instance.box.Z.Size()).Length()
+		padding := 0.01 * diagonal
+		instance.box = NewAABB(instance.box.X.Expand(padding), instance.box.Y.Expand(padding), instance.box.Z.Expand(padding))
+	}
+
+	return instance
+}
+
+// Returns the transformation at the given time
+func (instance MovingInstance) Transform(time float64) Transform {
+	alpha := 0.0
+	if instance.time1 != instance.time0 {
+		alpha = math.Max(0, math.Min(1, (time-instance.time0)/(instance.time1-instance.time0)))
+	}
+
+	return instance.start.Lerp(instance.end, alpha).Transform()
+}
+
+// Implement the Hittable interface
+func (instance MovingInstance) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	return Instance{object: instance.object, transform: instance.Transform(ray.Time())}.Hit(ray, rayTmin, rayTmax, rec)
+}
+
+func (instance MovingInstance) BoundingBox() AABB {
+	return instance.box
+}
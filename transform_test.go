@@ -0,0 +1,125 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func nearlyEqualVec3(a, b Vec3) bool {
+	return a.Sub(b).Length() < 1e-9
+}
+
+func TestTransform(t *testing.T) {
+	// Rotations are counterclockwise
+	if p := NewRotationZ(90).Point(NewPoint3(1, 0, 0)); !nearlyEqualVec3(p, NewPoint3(0, 1, 0)) {
+		t.Errorf("rotating X by 90 degrees around Z gave %v", p)
+	}
+
+	transform := NewScaling(1, 2, 3).Then(NewRotation(NewVec3(1, 1, 0), 30)).Then(NewTranslation(NewVec3(4, 5, 6)))
+
+	// The matrix and its inverse must be consistent, whether the inverse has been composed or computed
+	computed, err := NewTransform(transform.Matrix())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity := IdentityMatrix4()
+	for _, m := range []Matrix4{transform.m.Mul(transform.inv), transform.inv.Mul(transform.m), computed.inv.Mul(transform.m)} {
+		for i := 0; i < 4; i++ {
+			for j := 0; j < 4; j++ {
+				if math.Abs(m[i][j]-identity[i][j]) > 1e-9 {
+					t.Fatalf("matrix times inverse is not the identity: %v", m)
+				}
+			}
+		}
+	}
+
+	p := NewPoint3(1, 1, 1)
+	if got := transform.Inverse().Point(transform.Point(p)); !nearlyEqualVec3(got, p) {
+		t.Errorf("transforming back and forth gave %v", got)
+	}
+
+	// Normals stay perpendicular to transformed vectors
+	v, n := NewVec3(1, -1, 2), NewVec3(1, 1, 0)
+	if d := transform.Vector(v).Dot(transform.Normal(n)); math.Abs(d) > 1e-9 {
+		t.Errorf("transformed normal is not perpendicular, dot product %v", d)
+	}
+
+	if _, err := NewTransform(Matrix4{}); err == nil {
+		t.Error("no error inverting a singular matrix")
+	}
+}
+
+func TestInstanceHit(t *testing.T) {
+	// A unit sphere scaled and moved must behave like a bigger sphere in another place
+	instance := NewInstance(NewSphere(NewPoint3(0, 0, 0), 1), NewScaling(2, 2, 2).Then(NewRotationY(30)).Then(NewTranslation(NewVec3(1, 2, 3))))
+	sphere := NewSphere(NewPoint3(1, 2, 3), 2)
+
+	box := instance.BoundingBox()
+	if box.X.Min > -1+1e-9 || box.X.Max < 3-1e-9 || box.Y.Min > 1e-9 || box.Y.Max < 4-1e-9 || box.Z.Min > 1+1e-9 || box.Z.Max < 5-1e-9 {
+		t.Errorf("bounding box %v doesn't enclose the sphere", box)
+	}
+
+	rng := NewRng(1)
+
+	for i := 0; i < 1000; i++ {
+		ray := NewRay(rng.Vec3InInterval(-5, 5), rng.UnitVec3())
+
+		a, b := HitRecord{}, HitRecord{}
+		hitA := sphere.Hit(ray, 0.001, math.Inf(+1), &a)
+		hitB := instance.Hit(ray, 0.001, math.Inf(+1), &b)
+
+		if hitA != hitB {
+			t.Fatalf("ray %v: sphere hit %v, instance hit %v", ray, hitA, hitB)
+		}
+
+		if !hitA {
+			continue
+		}
+
+		if math.Abs(a.T-b.T) > 1e-9 || !nearlyEqualVec3(a.P, b.P) || !nearlyEqualVec3(a.Normal, b.Normal) || a.FrontFace != b.FrontFace {
+			t.Fatalf("ray %v: sphere hit %+v, instance hit %+v", ray, a, b)
+		}
+
+		if math.Abs(b.Tangent.Dot(b.Normal)) > 1e-9 || math.Abs(b.Tangent.Length()-1) > 1e-9 {
+			t.Fatalf("ray %v: bad tangent %v for normal %v", ray, b.Tangent, b.Normal)
+		}
+	}
+}
+
+func TestMovingInstance(t *testing.T) {
+	// A box spinning half a turn around the Y axis while moving up
+	start := NewPose()
+	end := NewPose()
+	end.Rotation = NewVec3(0, 180, 0)
+	end.Translation = NewVec3(0, 2, 0)
+
+	instance := NewMovingInstance(NewBox(NewPoint3(-1, -1, -1), NewPoint3(1, 1, 1), nil), start, end, 0, 1)
+
+	// A corner goes around an arc of radius sqrt(2), which the box must enclose
+	box := instance.BoundingBox()
+	if box.X.Min > -math.Sqrt2 || box.X.Max < math.Sqrt2 || box.Z.Min > -math.Sqrt2 || box.Z.Max < math.Sqrt2 || box.Y.Min > -1 || box.Y.Max < 3 {
+		t.Errorf("bounding box %v doesn't enclose the whole motion", box)
+	}
+
+	// Seen from above, the top face rises from 1 to 3
+	for _, time := range []float64{0, 0.25, 0.5, 1} {
+		rec := HitRecord{}
+		ray := NewRayWithTime(NewPoint3(0.5, 10, 0.5), NewVec3(0, -1, 0), time)
+
+		if !instance.Hit(ray, 0.001, math.Inf(+1), &rec) {
+			t.Fatalf("at time %v the ray should hit the box", time)
+		}
+
+		if want := 1 + 2*time; math.Abs(rec.P.Y-want) > 1e-9 || !nearlyEqualVec3(rec.Normal, NewVec3(0, 1, 0)) {
+			t.Errorf("at time %v got hit point %v and normal %v, want height %v", time, rec.P, rec.Normal, want)
+		}
+	}
+
+	// At a quarter of the motion the box has turned by 45 degrees, so its corner now sticks out along the X axis
+	rec := HitRecord{}
+	ray := NewRayWithTime(NewPoint3(1.3, 10, 0), NewVec3(0, -1, 0), 0.25)
+	if !instance.Hit(ray, 0.001, math.Inf(+1), &rec) {
+		t.Error("the turned box should be hit beyond its initial side")
+	}
+}
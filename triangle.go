@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Computes the intersection between a ray and the triangle v0, v1, v2 with the Möller–Trumbore algorithm.
+// Returns the ray parameter t and the barycentric coordinates u, v of the intersection point.
+//
+// Any point of the triangle plane can be written as v0 + u*(v1-v0) + v*(v2-v0), and it is inside the triangle if
+// u >= 0, v >= 0 and u+v <= 1. Equating it to the ray origin + t*direction gives a 3x3 linear system in t, u, v
+// that is solved with Cramer's rule, where all determinants are written as triple products.
+func hitTriangle(ray Ray, v0, v1, v2 Point3, rayTmin, rayTmax float64) (t, u, v float64, hit bool) {
+	edge1 := v1.Sub(v0)
+	edge2 := v2.Sub(v0)
+
+	pvec := ray.Direction().Cross(edge2)
+	det := edge1.Dot(pvec)
+
+	if math.Abs(det) < 1e-12 {
+		return 0, 0, 0, false // The ray is parallel to the triangle plane (or the triangle is degenerate)
+	}
+
+	invDet := 1 / det
+
+	tvec := ray.Origin().Sub(v0)
+	u = tvec.Dot(pvec) * invDet
+	if u < 0 || u > 1 {
+		return 0, 0, 0, false
+	}
+
+	qvec := tvec.Cross(edge1)
+	v = ray.Direction().Dot(qvec) * invDet
+	if v < 0 || u+v > 1 {
+		return 0, 0, 0, false
+	}
+
+	t = edge2.Dot(qvec) * invDet
+	if t <= rayTmin || t >= rayTmax {
+		return 0, 0, 0, false
+	}
+
+	return t, u, v, true
+}
+
+// Fills the hit record of a triangle. If vertex normals are given, the normal is interpolated across the surface so
+// that a mesh of flat triangles looks smooth. Likewise, if texture coordinates are given they are interpolated to
+// get the surface coordinates of the hit point, otherwise the barycentric coordinates are used.
+func setTriangleHitRecord(rec *HitRecord, ray Ray, t, u, v float64, v0, v1, v2 Point3, normals *[3]Vec3, uvs *[3]TexCoord, mat Material) {
+	rec.T = t
+	rec.P = ray.At(t)
+	rec.BaryU = u
+	rec.BaryV = v
+	rec.U = u
+	rec.V = v
+	rec.Mat = mat
+
+	// Without texture coordinates U grows along the first edge, since it's the barycentric coordinate of v1
+	edge1, edge2 := v1.Sub(v0), v2.Sub(v0)
+	dpdu := edge1
+
+	if uvs != nil {
+		rec.U = (1-u-v)*uvs[0].U + u*uvs[1].U + v*uvs[2].U
+		rec.V = (1-u-v)*uvs[0].V + u*uvs[1].V + v*uvs[2].V
+
+		// Solve edge = dpdu * du + dpdv * dv for both edges
+		du1, dv1 := uvs[1].U-uvs[0].U, uvs[1].V-uvs[0].V
+		du2, dv2 := uvs[2].U-uvs[0].U, uvs[2].V-uvs[0].V
+
+		if det := du1*dv2 - dv1*du2; det != 0 {
+			dpdu = edge1.Mul(dv2).Sub(edge2.Mul(dv1)).Div(det)
+		}
+	}
+
+	// The front face is determined by the geometric normal, i.e. by the order of the vertices (counterclockwise)
+	geometricNormal := edge1.Cross(edge2).UnitVector()
+	rec.SetFaceNormal(ray, geometricNormal)
+
+	if normals != nil {
+		n := normals[0].Mul(1 - u - v).Add(normals[1].Mul(u)).Add(normals[2].Mul(v)).UnitVector()
+		if !rec.FrontFace {
+			n = n.Negate()
+		}
+		rec.Normal = n
+	}
+
+	rec.SetTangentFrame(dpdu)
+}
+
+func triangleBoundingBox(v0, v1, v2 Point3) AABB {
+	box := NewSurroundingAABB(NewAABBFromPoints(v0, v1), NewAABBFromPoints(v2, v2))
+	return box.Pad(0.0001) // Triangles lying on an axis plane would have a box with zero thickness
+}
+
+// A standalone triangle
+type Triangle struct {
+	v0, v1, v2 Point3
+	normals    *[3]Vec3 // Optional vertex normals, for smooth shading
+	mat        Material
+}
+
+func NewTriangle(v0, v1, v2 Point3, mat Material) Triangle {
+	return Triangle{v0: v0, v1: v1, v2: v2, mat: mat}
+}
+
+func NewTriangleWithNormals(v0, v1, v2 Point3, n0, n1, n2 Vec3, mat Material) Triangle {
+	return Triangle{v0: v0, v1: v1, v2: v2, normals: &[3]Vec3{n0, n1, n2}, mat: mat}
+}
+
+// Implement the Hittable interface
+func (tr Triangle) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	t, u, v, hit := hitTriangle(ray, tr.v0, tr.v1, tr.v2, rayTmin, rayTmax)
+
+	if hit {
+		setTriangleHitRecord(rec, ray, t, u, v, tr.v0, tr.v1, tr.v2, tr.normals, nil, tr.mat)
+	}
+
+	return hit
+}
+
+func (tr Triangle) BoundingBox() AABB {
+	return triangleBoundingBox(tr.v0, tr.v1, tr.v2)
+}
+
+// Texture coordinates of a vertex
+type TexCoord struct {
+	U float64
+	V float64
+}
+
+// A triangle mesh stores each vertex once, no matter how many triangles share it.
+// Triangles are described by triplets of indices into the vertex array.
+type TriangleMesh struct {
+	vertices  []Point3
+	normals   []Vec3     // Either nil or one normal per vertex
+	texCoords []TexCoord // Either nil or one pair of texture coordinates per vertex
+	indices   []int
+	mat       Material
+	bvh       BVHNode
+}
+
+// A triangle of a mesh, it only stores the position of its first index
+type meshTriangle struct {
+	mesh  *TriangleMesh
+	index int
+}
+
+func NewTriangleMesh(vertices []Point3, normals []Vec3, indices []int, mat Material) (*TriangleMesh, error) {
+	if len(indices)%3 != 0 {
+		return nil, fmt.Errorf("the number of indices (%d) is not a multiple of 3", len(indices))
+	}
+
+	if normals != nil && len(normals) != len(vertices) {
+		return nil, fmt.Errorf("there are %d normals for %d vertices", len(normals), len(vertices))
+	}
+
+	for _, i := range indices {
+		if i < 0 || i >= len(vertices) {
+			return nil, fmt.Errorf("index %d is out of range [0, %d)", i, len(vertices))
+		}
+	}
+
+	mesh := &TriangleMesh{vertices: vertices, normals: normals, indices: indices, mat: mat}
+
+	triangles := NewHittableList()
+	for i := 0; i < len(indices); i += 3 {
+		triangles.Add(meshTriangle{mesh: mesh, index: i})
+	}
+	mesh.bvh = NewBVHNode(triangles)
+
+	return mesh, nil
+}
+
+// Assigns texture coordinates to the vertices of the mesh
+func (mesh *TriangleMesh) SetTexCoords(texCoords []TexCoord) error {
+	if texCoords != nil && len(texCoords) != len(mesh.vertices) {
+		return fmt.Errorf("there are %d texture coordinates for %d vertices", len(texCoords), len(mesh.vertices))
+	}
+
+	mesh.texCoords = texCoords
+
+	return nil
+}
+
+func (mesh *TriangleMesh) TriangleCount() int {
+	return len(mesh.indices) / 3
+}
+
+// Implement the Hittable interface
+func (mesh *TriangleMesh) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	return mesh.bvh.Hit(ray, rayTmin, rayTmax, rec)
+}
+
+func (mesh *TriangleMesh) BoundingBox() AABB {
+	return mesh.bvh.BoundingBox()
+}
+
+func (tr meshTriangle) vertices() (Point3, Point3, Point3) {
+	v := tr.mesh.vertices
+	i := tr.mesh.indices[tr.index : tr.index+3]
+	return v[i[0]], v[i[1]], v[i[2]]
+}
+
+func (tr meshTriangle) Hit(ray Ray, rayTmin, rayTmax float64, rec *HitRecord) bool {
+	v0, v1, v2 := tr.vertices()
+	t, u, v, hit := hitTriangle(ray, v0, v1, v2, rayTmin, rayTmax)
+
+	if hit {
+		var normals *[3]Vec3
+		var uvs *[3]TexCoord
+
+		i := tr.mesh.indices[tr.index : tr.index+3]
+
+		if tr.mesh.normals != nil {
+			normals = &[3]Vec3{tr.mesh.normals[i[0]], tr.mesh.normals[i[1]], tr.mesh.normals[i[2]]}
+		}
+
+		if tr.mesh.texCoords != nil {
+			uvs = &[3]TexCoord{tr.mesh.texCoords[i[0]], tr.mesh.texCoords[i[1]], tr.mesh.texCoords[i[2]]}
+		}
+
+		setTriangleHitRecord(rec, ray, t, u, v, v0, v1, v2, normals, uvs, tr.mesh.mat)
+	}
+
+	return hit
+}
+
+func (tr meshTriangle) BoundingBox() AABB {
+	return triangleBoundingBox(tr.vertices())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTriangleHit(t *testing.T) {
+	tr := NewTriangle(NewPoint3(0, 0, -1), NewPoint3(1, 0, -1), NewPoint3(0, 1, -1), nil)
+	origin := NewPoint3(0, 0, 0)
+
+	rec := HitRecord{}
+	if !tr.Hit(NewRay(origin, NewVec3(0.25, 0.5, -1)), 0, math.Inf(+1), &rec) {
+		t.Fatalf("ray should hit the triangle")
+	}
+
+	if rec.T != 1 || rec.BaryU != 0.25 || rec.BaryV != 0.5 {
+		t.Errorf("got t=%f u=%f v=%f, want t=1 u=0.25 v=0.5", rec.T, rec.BaryU, rec.BaryV)
+	}
+
+	// Vertices are counterclockwise as seen from the ray origin
+	if !rec.FrontFace || rec.Normal != NewVec3(0, 0, 1) {
+		t.Errorf("got front face %v and normal %v", rec.FrontFace, rec.Normal)
+	}
+
+	// Without texture coordinates the tangent follows the first edge
+	if rec.Tangent != NewVec3(1, 0, 0) || rec.Bitangent != NewVec3(0, 1, 0) {
+		t.Errorf("got tangent %v and bitangent %v", rec.Tangent, rec.Bitangent)
+	}
+
+	if tr.Hit(NewRay(origin, NewVec3(0.6, 0.6, -1)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray outside the triangle should miss it")
+	}
+
+	if tr.Hit(NewRay(origin, NewVec3(1, 0, 0)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray parallel to the triangle should miss it")
+	}
+}
+
+func TestTriangleMesh(t *testing.T) {
+	// A unit square made of two triangles, with normals tilted towards the center
+	vertices := []Point3{NewPoint3(-1, -1, 0), NewPoint3(1, -1, 0), NewPoint3(1, 1, 0), NewPoint3(-1, 1, 0)}
+	normals := []Vec3{NewVec3(-1, -1, 1).UnitVector(), NewVec3(1, -1, 1).UnitVector(), NewVec3(1, 1, 1).UnitVector(), NewVec3(-1, 1, 1).UnitVector()}
+	mesh, err := NewTriangleMesh(vertices, normals, []int{0, 1, 2, 0, 2, 3}, nil)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rec := HitRecord{}
+	if !mesh.Hit(NewRay(NewPoint3(0.5, 0.5, 5), NewVec3(0, 0, -1)), 0, math.Inf(+1), &rec) {
+		t.Fatalf("ray should hit the mesh")
+	}
+
+	if rec.T != 5 || !rec.FrontFace || rec.Normal.X <= 0 || rec.Normal.Y <= 0 {
+		t.Errorf("unexpected hit record %+v", rec)
+	}
+
+	if mesh.Hit(NewRay(NewPoint3(1.5, 0.5, 5), NewVec3(0, 0, -1)), 0, math.Inf(+1), &rec) {
+		t.Errorf("ray outside the mesh should miss it")
+	}
+
+	if _, err := NewTriangleMesh(vertices, nil, []int{0, 1, 4}, nil); err == nil {
+		t.Errorf("out of range index should be reported")
+	}
+}
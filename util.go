@@ -3,22 +3,84 @@ package main
 import (
 	"math"
 	"math/rand"
+	"time"
 )
 
 func DegreesToRadians(degrees float64) float64 {
 	return degrees * math.Pi / 180
 }
 
+// Rng is a pseudo-random number generator. It is not safe for concurrent use, so every rendering worker owns its own
+// instance: this way workers don't contend on the lock that protects a shared source.
+type Rng struct {
+	r *rand.Rand
+}
+
+func NewRng(seed int64) *Rng {
+	return &Rng{r: rand.New(rand.NewSource(seed))}
+}
+
+// Restarts the sequence of random numbers from the given seed
+func (rng *Rng) Seed(seed int64) {
+	rng.r.Seed(seed)
+}
+
+// Returns a non-negative random 63-bit integer, it is mostly useful to derive new seeds
+func (rng *Rng) Int63() int64 {
+	return rng.r.Int63()
+}
+
+// Returns a random number in the interval [0,1)
+func (rng *Rng) Double() float64 {
+	return rng.r.Float64()
+}
+
+// Returns a random number in the interval [min, max)
+func (rng *Rng) DoubleInInterval(min, max float64) float64 {
+	return min + (max-min)*rng.Double()
+}
+
+// The global generator is used by code that runs on the main goroutine only, e.g. to build the scenes.
+// Rendering workers must use their own generator instead.
+var globalRng = NewRng(time.Now().UnixNano())
+
+// Makes the global generator, and therefore the whole rendering, reproducible
+func SetRandomSeed(seed int64) {
+	globalRng.Seed(seed)
+}
+
 // Returns a random number in the interval [0,1)
 func RandomDouble() float64 {
-	return rand.Float64()
+	return globalRng.Double()
 }
 
 // Returns a random number in the interval [min, max)
 func RandomDoubleInInterval(min, max float64) float64 {
-	return min + (max-min)*RandomDouble()
+	return globalRng.DoubleInInterval(min, max)
 }
 
 func LinearToGamma(linear float64) float64 {
 	return math.Sqrt(linear)
 }
+
+// Inverse of LinearToGamma(), converts the colors of an image file back to linear values
+func GammaToLinear(gamma float64) float64 {
+	return gamma * gamma
+}
+
+// Returns the brightness of a linear color as perceived by the human eye (Rec. 709 weights)
+func Luminance(c Color) float64 {
+	return 0.2126*c.X + 0.7152*c.Y + 0.0722*c.Z
+}
+
+func clampInt(x, min, max int) int {
+	if x < min {
+		return min
+	}
+
+	if x > max {
+		return max
+	}
+
+	return x
+}
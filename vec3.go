@@ -63,30 +63,53 @@ func (v Vec3) UnitVector() Vec3 {
 	return v.Div(v.Length())
 }
 
+// Returns the component of the given axis: 0 is X, 1 is Y, 2 is Z
+func (v Vec3) Axis(n int) float64 {
+	switch n {
+	case 1:
+		return v.Y
+	case 2:
+		return v.Z
+	}
+
+	return v.X
+}
+
+// Returns two unit vectors that form an orthonormal basis (t, b, n) together with the unit vector n
+func orthonormalBasis(n Vec3) (t, b Vec3) {
+	axis := NewVec3(1, 0, 0)
+	if math.Abs(n.X) > 0.9 {
+		axis = NewVec3(0, 1, 0) // Avoid an axis almost parallel to n
+	}
+
+	t = axis.Sub(n.Mul(n.Dot(axis))).UnitVector()
+	return t, n.Cross(t)
+}
+
 // These functions create a random vector with various constraints, they are used to simulate diffuse reflection
-func NewRandomVec3() Vec3 {
-	return NewVec3(RandomDouble(), RandomDouble(), RandomDouble())
+func (rng *Rng) Vec3() Vec3 {
+	return NewVec3(rng.Double(), rng.Double(), rng.Double())
 }
 
-func NewRandomInIntervalVec3(min, max float64) Vec3 {
-	return NewVec3(RandomDoubleInInterval(min, max), RandomDoubleInInterval(min, max), RandomDoubleInInterval(min, max))
+func (rng *Rng) Vec3InInterval(min, max float64) Vec3 {
+	return NewVec3(rng.DoubleInInterval(min, max), rng.DoubleInInterval(min, max), rng.DoubleInInterval(min, max))
 }
 
-func NewRandomInUnitSphereVec3() Vec3 {
+func (rng *Rng) InUnitSphereVec3() Vec3 {
 	for {
-		p := NewRandomInIntervalVec3(-1, 1) // Create a random vector inside a cube
-		if p.LengthSquared() <= 1 {         // If the length of the vector is less than 1 then the vector is inside a sphere (centered at the origin)
+		p := rng.Vec3InInterval(-1, 1) // Create a random vector inside a cube
+		if p.LengthSquared() <= 1 {    // If the length of the vector is less than 1 then the vector is inside a sphere (centered at the origin)
 			return p
 		}
 	}
 }
 
-func NewRandomUnitVec3() Vec3 {
-	return NewRandomInUnitSphereVec3().UnitVector()
+func (rng *Rng) UnitVec3() Vec3 {
+	return rng.InUnitSphereVec3().UnitVector()
 }
 
-func NewRandomUnitInHemisphereVec3(normal Vec3) Vec3 {
-	vecOnUnitSphere := NewRandomInUnitSphereVec3().UnitVector()
+func (rng *Rng) UnitInHemisphereVec3(normal Vec3) Vec3 {
+	vecOnUnitSphere := rng.InUnitSphereVec3().UnitVector()
 	if vecOnUnitSphere.Dot(normal) > 0 {
 		return vecOnUnitSphere
 	} else {
@@ -94,6 +117,27 @@ func NewRandomUnitInHemisphereVec3(normal Vec3) Vec3 {
 	}
 }
 
+// Same as above, using the global generator
+func NewRandomVec3() Vec3 {
+	return globalRng.Vec3()
+}
+
+func NewRandomInIntervalVec3(min, max float64) Vec3 {
+	return globalRng.Vec3InInterval(min, max)
+}
+
+func NewRandomInUnitSphereVec3() Vec3 {
+	return globalRng.InUnitSphereVec3()
+}
+
+func NewRandomUnitVec3() Vec3 {
+	return globalRng.UnitVec3()
+}
+
+func NewRandomUnitInHemisphereVec3(normal Vec3) Vec3 {
+	return globalRng.UnitInHemisphereVec3(normal)
+}
+
 // Checks whether the vector is close to zero
 func (v Vec3) NearZero() bool {
 	s := 1e-8